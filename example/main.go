@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/WelcomerTeam/Discord/discord"
@@ -68,15 +70,17 @@ func main() {
 	logger := zerolog.New(writer).With().Timestamp().Logger()
 	logger.Info().Msg("Logging configured")
 
-	context, cancel := context.WithCancel(context.Background())
+	// Cancelling the context will gracefully shutdown the app.
+	context, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 
 	// Setup app.
 	app, err := subway.NewSubway(context, subway.SubwayOptions{
-		SandwichClient:    protobuf.NewSandwichClient(grpcConnection),
-		RESTInterface:     restInterface,
-		Logger:            logger,
-		PublicKeys:        *publicKeys,
-		PrometheusAddress: *prometheusAddress,
+		SandwichClient:     protobuf.NewSandwichClient(grpcConnection),
+		SandwichConnection: grpcConnection,
+		RESTInterface:      restInterface,
+		Logger:             logger,
+		PublicKeys:         *publicKeys,
+		PrometheusAddress:  *prometheusAddress,
 	})
 	if err != nil {
 		logger.Panic().Err(err).Msg("Exception creating app")
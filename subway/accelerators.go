@@ -1,5 +1,7 @@
 package internal
 
+import "github.com/WelcomerTeam/Discord/discord"
+
 // Accelerator to create a subcommand group.
 func NewSubcommandGroup(name, description string) *InteractionCommandable {
 	return SetupInteractionCommandable(&InteractionCommandable{
@@ -9,3 +11,15 @@ func NewSubcommandGroup(name, description string) *InteractionCommandable {
 		Type: InteractionCommandableTypeSubcommandGroup,
 	})
 }
+
+// Accelerator to create a modal response. Text inputs must each be wrapped in an action row.
+func NewModalResponse(customID, title string, components ...discord.InteractionComponent) *discord.InteractionResponse {
+	return &discord.InteractionResponse{
+		Type: discord.InteractionCallbackTypeModal,
+		Data: &discord.InteractionCallbackData{
+			CustomID:   customID,
+			Title:      title,
+			Components: components,
+		},
+	}
+}
@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	discord "github.com/WelcomerTeam/Discord/discord"
+)
+
+// Application is a bot application served by subway. Each application has its own
+// command tree, public keys and REST session. Interactions are routed to an application
+// by their application ID.
+type Application struct {
+	ID discord.Snowflake
+
+	// Commands is the command tree for the application. Its ErrorHandler is
+	// used as the root error handler for the application.
+	Commands *InteractionCommandable `json:"-"`
+
+	Cogs map[string]Cog `json:"-"`
+
+	RESTInterface discord.RESTInterface `json:"-"`
+	EmptySession  *discord.Session      `json:"-"`
+
+	// DevelopmentGuildID is the guild development commands are registered in.
+	DevelopmentGuildID discord.Snowflake
+
+	publicKeys publicKeySet
+	token      string
+
+	// Internal for registering cogs.
+	subway *Subway
+}
+
+// ApplicationOptions represents the options to register a new application.
+type ApplicationOptions struct {
+	ID discord.Snowflake
+
+	// Public key(s) for signature validation. Comma delimited. Interactions for the
+	// application are only accepted if signed by one of these keys.
+	PublicKeys string
+
+	// REST interface to use for the application. Defaults to the subway REST interface.
+	RESTInterface discord.RESTInterface
+
+	// Token used when syncing commands. Token must have "Bot " added.
+	Token string
+
+	// Guild that commands with a development scope are registered in.
+	// Defaults to the subway development guild.
+	DevelopmentGuildID discord.Snowflake
+}
+
+// MustRegisterApplication will attempt to do RegisterApplication and will panic if not possible.
+func (sub *Subway) MustRegisterApplication(options ApplicationOptions) *Application {
+	application, err := sub.RegisterApplication(options)
+	if err != nil {
+		panic(fmt.Sprintf(`sandwich: RegisterApplication(%d): %v`, options.ID, err.Error()))
+	}
+
+	return application
+}
+
+// RegisterApplication registers an application, so interactions with its
+// application ID are routed to its own command tree.
+func (sub *Subway) RegisterApplication(options ApplicationOptions) (*Application, error) {
+	if options.ID.IsNil() {
+		return nil, ErrMissingApplicationID
+	}
+
+	if options.RESTInterface == nil {
+		options.RESTInterface = sub.RESTInterface
+	}
+
+	if options.DevelopmentGuildID.IsNil() {
+		options.DevelopmentGuildID = sub.DevelopmentGuildID
+	}
+
+	application := &Application{
+		ID:            options.ID,
+		Commands:      SetupInteractionCommandable(nil),
+		Cogs:          make(map[string]Cog),
+		RESTInterface: options.RESTInterface,
+		EmptySession:  discord.NewSession("", options.RESTInterface),
+
+		DevelopmentGuildID: options.DevelopmentGuildID,
+		token:              options.Token,
+		subway:             sub,
+	}
+
+	application.Commands.converters = sub.Converters
+
+	err := application.SetPublicKeys(options.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.ApplicationsMu.Lock()
+	defer sub.ApplicationsMu.Unlock()
+
+	if _, ok := sub.Applications[options.ID]; ok {
+		return nil, ErrApplicationAlreadyRegistered
+	}
+
+	sub.Applications[options.ID] = application
+
+	sub.Logger.Info().Int64("application_id", int64(options.ID)).Msg("Registered application")
+
+	return application, nil
+}
+
+// GetApplication returns a registered application by its ID.
+func (sub *Subway) GetApplication(applicationID discord.Snowflake) *Application {
+	sub.ApplicationsMu.RLock()
+	defer sub.ApplicationsMu.RUnlock()
+
+	return sub.Applications[applicationID]
+}
+
+// GetApplications returns all registered applications.
+func (sub *Subway) GetApplications() []*Application {
+	sub.ApplicationsMu.RLock()
+	defer sub.ApplicationsMu.RUnlock()
+
+	applications := make([]*Application, 0, len(sub.Applications))
+
+	for _, application := range sub.Applications {
+		applications = append(applications, application)
+	}
+
+	return applications
+}
+
+// MustRegisterCog will attempt to do RegisterCog and will panic if not possible.
+func (application *Application) MustRegisterCog(cog Cog) {
+	if err := application.RegisterCog(cog); err != nil {
+		panic(fmt.Sprintf(`sandwich: RegisterCog(%v): %v`, cog, err.Error()))
+	}
+}
+
+// RegisterCog registers a cog, adding its commands to the application command tree.
+func (application *Application) RegisterCog(cog Cog) error {
+	return application.subway.registerCog(application.Cogs, application.Commands, cog)
+}
+
+// SetPublicKeys replaces all public keys used for signature validation.
+// Public keys are hex encoded and comma delimited.
+func (application *Application) SetPublicKeys(publicKeys string) error {
+	return application.publicKeys.set(publicKeys)
+}
+
+// AddPublicKey adds a hex encoded public key used for signature validation.
+func (application *Application) AddPublicKey(publicKey string) error {
+	return application.publicKeys.add(publicKey)
+}
+
+// RemovePublicKey retires a hex encoded public key. Returns false if the key was not present.
+func (application *Application) RemovePublicKey(publicKey string) (bool, error) {
+	return application.publicKeys.remove(publicKey)
+}
+
+// SyncCommands syncs all commands registered to the application with the discord API.
+func (application *Application) SyncCommands(ctx context.Context) error {
+	if application.token == "" {
+		return ErrMissingApplicationToken
+	}
+
+	session := discord.NewSession(application.token, application.RESTInterface)
+
+	scopedCommands := application.subway.addStaleCommandGuilds(application.ID, application.Commands.MapScopedApplicationCommands(application.DevelopmentGuildID))
+
+	err := syncScopedApplicationCommands(ctx, session, application.ID, scopedCommands)
+	if err != nil {
+		return err
+	}
+
+	application.subway.recordSyncedCommandGuilds(application.ID, scopedCommands)
+
+	return nil
+}
+
+// SyncAllCommands syncs the commands of every registered application with the discord API.
+// Commands registered directly to subway are not synced, use SyncCommands instead.
+func (sub *Subway) SyncAllCommands(ctx context.Context) error {
+	var errs []error
+
+	for _, application := range sub.GetApplications() {
+		err := application.SyncCommands(ctx)
+		if err != nil {
+			sub.Logger.Error().Err(err).Int64("application_id", int64(application.ID)).Msg("Failed to sync commands")
+
+			errs = append(errs, fmt.Errorf("application %d: %w", application.ID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// addApplicationToContext adds the application the interaction belongs to, if it has been registered.
+func (sub *Subway) addApplicationToContext(ctx context.Context, interaction discord.Interaction) context.Context {
+	application := sub.GetApplication(interaction.ApplicationID)
+	if application == nil {
+		return ctx
+	}
+
+	return AddApplicationToContext(ctx, application)
+}
+
+// getCommands returns the command tree for the application in the context.
+func (sub *Subway) getCommands(ctx context.Context) *InteractionCommandable {
+	if application, ok := ctx.Value(ApplicationKey).(*Application); ok {
+		return application.Commands
+	}
+
+	return sub.Commands
+}
+
+// getSession returns the session with the token of the application in the context.
+// If there is no token, the session does not have a token.
+func (sub *Subway) getSession(ctx context.Context) *discord.Session {
+	if application, ok := ctx.Value(ApplicationKey).(*Application); ok {
+		return discord.NewSession(application.token, application.RESTInterface)
+	}
+
+	return discord.NewSession(sub.token, sub.RESTInterface)
+}
+
+// getEmptySession returns the session without a token for the application in the context.
+func (sub *Subway) getEmptySession(ctx context.Context) *discord.Session {
+	if application, ok := ctx.Value(ApplicationKey).(*Application); ok {
+		return application.EmptySession
+	}
+
+	return sub.EmptySession
+}
@@ -0,0 +1,599 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// Struct tags used to declare arguments on an argument struct.
+const (
+	argumentTagName         = "arg"
+	argumentTagDescription  = "description"
+	argumentTagRequired     = "required"
+	argumentTagType         = "type"
+	argumentTagMin          = "min"
+	argumentTagMax          = "max"
+	argumentTagMinLength    = "min_length"
+	argumentTagMaxLength    = "max_length"
+	argumentTagChoices      = "choices"
+	argumentTagChannelTypes = "channel_types"
+	argumentTagAutocomplete = "autocomplete"
+)
+
+// argumentTypeNames are the names that can be used in the type tag of an argument struct.
+var argumentTypeNames = map[string]ArgumentType{
+	"snowflake":        ArgumentTypeSnowflake,
+	"member":           ArgumentTypeMember,
+	"user":             ArgumentTypeUser,
+	"text_channel":     ArgumentTypeTextChannel,
+	"guild":            ArgumentTypeGuild,
+	"role":             ArgumentTypeRole,
+	"colour":           ArgumentTypeColour,
+	"voice_channel":    ArgumentTypeVoiceChannel,
+	"stage_channel":    ArgumentTypeStageChannel,
+	"emoji":            ArgumentTypeEmoji,
+	"partial_emoji":    ArgumentTypePartialEmoji,
+	"category_channel": ArgumentTypeCategoryChannel,
+	"store_channel":    ArgumentTypeStoreChannel,
+	"thread":           ArgumentTypeThread,
+	"guild_channel":    ArgumentTypeGuildChannel,
+	"string":           ArgumentTypeString,
+	"bool":             ArgumentTypeBool,
+	"int":              ArgumentTypeInt,
+	"float":            ArgumentTypeFloat,
+	"strings":          ArgumentTypeStrings,
+	"attachment":       ArgumentTypeAttachment,
+	"mentionable":      ArgumentTypeMentionable,
+	"duration":         ArgumentTypeDuration,
+	"timestamp":        ArgumentTypeTimestamp,
+	"timezone":         ArgumentTypeTimezone,
+	"message":          ArgumentTypeMessage,
+	"invite":           ArgumentTypeInvite,
+	"webhook":          ArgumentTypeWebhook,
+}
+
+// argumentGoTypes are the types built-in converters output, used to infer the argument
+// type of a field and to check fields are able to hold the converted value.
+var argumentGoTypes = map[ArgumentType]reflect.Type{
+	ArgumentTypeSnowflake:       reflect.TypeFor[discord.Snowflake](),
+	ArgumentTypeMember:          reflect.TypeFor[discord.GuildMember](),
+	ArgumentTypeUser:            reflect.TypeFor[discord.User](),
+	ArgumentTypeTextChannel:     reflect.TypeFor[discord.Channel](),
+	ArgumentTypeGuild:           reflect.TypeFor[discord.Guild](),
+	ArgumentTypeRole:            reflect.TypeFor[discord.Role](),
+	ArgumentTypeColour:          reflect.TypeFor[color.RGBA](),
+	ArgumentTypeVoiceChannel:    reflect.TypeFor[discord.Channel](),
+	ArgumentTypeStageChannel:    reflect.TypeFor[discord.Channel](),
+	ArgumentTypeEmoji:           reflect.TypeFor[discord.Emoji](),
+	ArgumentTypePartialEmoji:    reflect.TypeFor[discord.Emoji](),
+	ArgumentTypeCategoryChannel: reflect.TypeFor[discord.Channel](),
+	ArgumentTypeStoreChannel:    reflect.TypeFor[discord.Channel](),
+	ArgumentTypeThread:          reflect.TypeFor[discord.Channel](),
+	ArgumentTypeGuildChannel:    reflect.TypeFor[discord.Channel](),
+	ArgumentTypeString:          reflect.TypeFor[string](),
+	ArgumentTypeBool:            reflect.TypeFor[bool](),
+	ArgumentTypeInt:             reflect.TypeFor[int64](),
+	ArgumentTypeFloat:           reflect.TypeFor[float64](),
+	ArgumentTypeStrings:         reflect.TypeFor[[]string](),
+	ArgumentTypeAttachment:      reflect.TypeFor[discord.MessageAttachment](),
+	ArgumentTypeMentionable:     reflect.TypeFor[Mentionable](),
+	ArgumentTypeDuration:        reflect.TypeFor[time.Duration](),
+	ArgumentTypeTimestamp:       reflect.TypeFor[time.Time](),
+	ArgumentTypeTimezone:        reflect.TypeFor[*time.Location](),
+	ArgumentTypeMessage:         reflect.TypeFor[discord.Message](),
+	ArgumentTypeInvite:          reflect.TypeFor[discord.Invite](),
+	ArgumentTypeWebhook:         reflect.TypeFor[discord.Webhook](),
+}
+
+// inferredArgumentTypes are the argument types used for fields without a type tag.
+var inferredArgumentTypes = map[reflect.Type]ArgumentType{
+	reflect.TypeFor[discord.Snowflake]():         ArgumentTypeSnowflake,
+	reflect.TypeFor[discord.GuildMember]():       ArgumentTypeMember,
+	reflect.TypeFor[discord.User]():              ArgumentTypeUser,
+	reflect.TypeFor[discord.Channel]():           ArgumentTypeGuildChannel,
+	reflect.TypeFor[discord.Guild]():             ArgumentTypeGuild,
+	reflect.TypeFor[discord.Role]():              ArgumentTypeRole,
+	reflect.TypeFor[color.RGBA]():                ArgumentTypeColour,
+	reflect.TypeFor[discord.Emoji]():             ArgumentTypeEmoji,
+	reflect.TypeFor[string]():                    ArgumentTypeString,
+	reflect.TypeFor[bool]():                      ArgumentTypeBool,
+	reflect.TypeFor[int64]():                     ArgumentTypeInt,
+	reflect.TypeFor[float64]():                   ArgumentTypeFloat,
+	reflect.TypeFor[[]string]():                  ArgumentTypeStrings,
+	reflect.TypeFor[discord.MessageAttachment](): ArgumentTypeAttachment,
+	reflect.TypeFor[Mentionable]():               ArgumentTypeMentionable,
+	reflect.TypeFor[time.Duration]():             ArgumentTypeDuration,
+	reflect.TypeFor[time.Time]():                 ArgumentTypeTimestamp,
+	reflect.TypeFor[*time.Location]():            ArgumentTypeTimezone,
+	reflect.TypeFor[discord.Message]():           ArgumentTypeMessage,
+	reflect.TypeFor[discord.Invite]():            ArgumentTypeInvite,
+	reflect.TypeFor[discord.Webhook]():           ArgumentTypeWebhook,
+}
+
+// argumentStructField maps an argument to the field of an argument struct.
+type argumentStructField struct {
+	name  string
+	index int
+}
+
+// argumentStruct is the argument struct of a command, once it has been parsed.
+type argumentStruct struct {
+	structType reflect.Type
+	fields     []argumentStructField
+}
+
+// ArgumentParametersFromStruct derives the argument parameters of a command from the fields of a struct.
+// Exported fields are arguments, named by the arg tag or the lowercase field name. Fields tagged with
+// arg:"-" are ignored. A field is optional unless it is tagged required:"true", and optional arguments
+// that are not provided are set to their default, unless the field is a pointer. The argument type
+// is inferred from the field type, or can be set with the type tag, such as type:"member". The min
+// and max tags of float fields may be fractional, with the same limitations as MinNumber and MaxNumber.
+//
+//	type BanArguments struct {
+//		Member discord.GuildMember `arg:"member" description:"Member to ban" required:"true"`
+//		Reason string              `description:"Reason for the ban" max_length:"512"`
+//		Days   int64               `description:"Days of messages to delete" min:"0" max:"7"`
+//	}
+func ArgumentParametersFromStruct(value interface{}) ([]ArgumentParameter, error) {
+	_, argumentParameters, err := parseArgumentStruct(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArgumentStruct, err)
+	}
+
+	return argumentParameters, nil
+}
+
+func parseArgumentStruct(value interface{}) (*argumentStruct, []ArgumentParameter, error) {
+	structType := reflect.TypeOf(value)
+	if structType != nil && structType.Kind() == reflect.Pointer {
+		structType = structType.Elem()
+	}
+
+	if structType == nil || structType.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%T is not a struct", value)
+	}
+
+	parsed := &argumentStruct{
+		structType: structType,
+		fields:     make([]argumentStructField, 0, structType.NumField()),
+	}
+
+	argumentParameters := make([]ArgumentParameter, 0, structType.NumField())
+
+	for i := range structType.NumField() {
+		field := structType.Field(i)
+
+		name, ok := field.Tag.Lookup(argumentTagName)
+		if name == "-" || !field.IsExported() {
+			if ok && name != "-" {
+				return nil, nil, fmt.Errorf("field %s is not exported", field.Name)
+			}
+
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		argumentParameter, err := parseArgumentStructField(field, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %w", field.Name, err)
+		}
+
+		argumentParameters = append(argumentParameters, argumentParameter)
+		parsed.fields = append(parsed.fields, argumentStructField{name: name, index: i})
+	}
+
+	return parsed, argumentParameters, nil
+}
+
+func parseArgumentStructField(field reflect.StructField, name string) (argumentParameter ArgumentParameter, err error) {
+	argumentParameter = ArgumentParameter{
+		Name:        name,
+		Description: field.Tag.Get(argumentTagDescription),
+	}
+
+	fieldType := field.Type
+	if fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+
+	if typeName, ok := field.Tag.Lookup(argumentTagType); ok {
+		argumentType, ok := argumentTypeNames[typeName]
+		if !ok {
+			// Custom converters are referenced by their argument type.
+			value, parseErr := strconv.ParseUint(typeName, 10, 16)
+			if parseErr != nil {
+				return argumentParameter, fmt.Errorf("unknown argument type %q", typeName)
+			}
+
+			argumentType = ArgumentType(value)
+		}
+
+		argumentParameter.ArgumentType = argumentType
+	} else {
+		argumentType, ok := inferredArgumentTypes[field.Type]
+		if !ok {
+			argumentType, ok = inferredArgumentTypes[fieldType]
+		}
+
+		switch {
+		case ok:
+		case fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Int64:
+			argumentType, ok = ArgumentTypeInt, true
+		case fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64:
+			argumentType, ok = ArgumentTypeFloat, true
+		}
+
+		if !ok {
+			return argumentParameter, fmt.Errorf("argument type of %s is not able to be inferred, use the type tag", fieldType)
+		}
+
+		argumentParameter.ArgumentType = argumentType
+	}
+
+	if goType, ok := argumentGoTypes[argumentParameter.ArgumentType]; ok && !isAssignableArgument(goType, fieldType) && !goType.AssignableTo(field.Type) {
+		return argumentParameter, fmt.Errorf("%s is not able to hold a %s argument", field.Type, goType)
+	}
+
+	argumentParameter.Required, err = parseOptionalTag(field, argumentTagRequired, strconv.ParseBool)
+	if err != nil {
+		return argumentParameter, err
+	}
+
+	autocomplete, err := parseOptionalTag(field, argumentTagAutocomplete, strconv.ParseBool)
+	if err != nil {
+		return argumentParameter, err
+	}
+
+	if autocomplete {
+		argumentParameter.Autocomplete = &autocomplete
+	}
+
+	intTags := map[string]**int32{
+		argumentTagMinLength: &argumentParameter.MinLength,
+		argumentTagMaxLength: &argumentParameter.MaxLength,
+	}
+
+	// Float arguments accept fractional bounds.
+	if argumentParameter.ArgumentType == ArgumentTypeFloat {
+		for tag, target := range map[string]**float64{
+			argumentTagMin: &argumentParameter.MinNumber,
+			argumentTagMax: &argumentParameter.MaxNumber,
+		} {
+			if value, ok := field.Tag.Lookup(tag); ok {
+				bound, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return argumentParameter, fmt.Errorf("%s tag %q is not a valid number", tag, value)
+				}
+
+				*target = &bound
+			}
+		}
+	} else {
+		intTags[argumentTagMin] = &argumentParameter.MinValue
+		intTags[argumentTagMax] = &argumentParameter.MaxValue
+	}
+
+	for tag, target := range intTags {
+		if value, ok := field.Tag.Lookup(tag); ok {
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return argumentParameter, fmt.Errorf("%s tag %q is not a valid integer", tag, value)
+			}
+
+			bound := int32(parsed)
+			*target = &bound
+		}
+	}
+
+	err = applyArgumentKindBounds(&argumentParameter, fieldType)
+	if err != nil {
+		return argumentParameter, err
+	}
+
+	if value, ok := field.Tag.Lookup(argumentTagChannelTypes); ok {
+		for _, channelType := range strings.Split(value, ",") {
+			parsed, err := strconv.ParseUint(strings.TrimSpace(channelType), 10, 16)
+			if err != nil {
+				return argumentParameter, fmt.Errorf("channel type %q is not a valid integer", channelType)
+			}
+
+			argumentParameter.ChannelTypes = append(argumentParameter.ChannelTypes, discord.ChannelType(parsed))
+		}
+	}
+
+	if value, ok := field.Tag.Lookup(argumentTagChoices); ok {
+		argumentParameter.Choices, err = parseArgumentChoices(argumentParameter.ArgumentType, value)
+		if err != nil {
+			return argumentParameter, err
+		}
+	}
+
+	return argumentParameter, nil
+}
+
+// parseArgumentChoices parses choices in the format "name=value,name=value". If a choice has no name,
+// the value is used as the name.
+func parseArgumentChoices(argumentType ArgumentType, value string) ([]discord.ApplicationCommandOptionChoice, error) {
+	choices := make([]discord.ApplicationCommandOptionChoice, 0)
+
+	for _, choice := range strings.Split(value, ",") {
+		name, choiceValue, ok := strings.Cut(choice, "=")
+		if !ok {
+			choiceValue = name
+		}
+
+		var rawValue json.RawMessage
+
+		var err error
+
+		switch argumentType {
+		case ArgumentTypeInt:
+			var parsed int64
+
+			parsed, err = strconv.ParseInt(choiceValue, 10, 64)
+			rawValue = json.RawMessage(strconv.FormatInt(parsed, 10))
+		case ArgumentTypeFloat:
+			var parsed float64
+
+			parsed, err = strconv.ParseFloat(choiceValue, 64)
+			rawValue = json.RawMessage(strconv.FormatFloat(parsed, 'f', -1, 64))
+		default:
+			rawValue, err = json.Marshal(choiceValue)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("choice %q is not valid for the argument type", choiceValue)
+		}
+
+		choices = append(choices, discord.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: rawValue,
+		})
+	}
+
+	return choices, nil
+}
+
+func parseOptionalTag[T any](field reflect.StructField, tag string, parse func(string) (T, error)) (T, error) {
+	var value T
+
+	tagValue, ok := field.Tag.Lookup(tag)
+	if !ok {
+		return value, nil
+	}
+
+	value, err := parse(tagValue)
+	if err != nil {
+		return value, fmt.Errorf("%s tag %q is not valid", tag, tagValue)
+	}
+
+	return value, nil
+}
+
+// applyArgumentKindBounds sets the bounds of integer arguments held by fields smaller than an int64,
+// so discord only accepts values the field is able to hold.
+func applyArgumentKindBounds(argumentParameter *ArgumentParameter, fieldType reflect.Type) error {
+	if argumentParameter.ArgumentType != ArgumentTypeInt {
+		return nil
+	}
+
+	var minBound, maxBound int32
+
+	switch fieldType.Kind() {
+	case reflect.Int8:
+		minBound, maxBound = math.MinInt8, math.MaxInt8
+	case reflect.Int16:
+		minBound, maxBound = math.MinInt16, math.MaxInt16
+	case reflect.Int32:
+		minBound, maxBound = math.MinInt32, math.MaxInt32
+	default:
+		return nil
+	}
+
+	switch {
+	case argumentParameter.MinValue == nil:
+		argumentParameter.MinValue = &minBound
+	case *argumentParameter.MinValue < minBound || *argumentParameter.MinValue > maxBound:
+		return fmt.Errorf("%s tag %d does not fit in %s", argumentTagMin, *argumentParameter.MinValue, fieldType)
+	}
+
+	switch {
+	case argumentParameter.MaxValue == nil:
+		argumentParameter.MaxValue = &maxBound
+	case *argumentParameter.MaxValue < minBound || *argumentParameter.MaxValue > maxBound:
+		return fmt.Errorf("%s tag %d does not fit in %s", argumentTagMax, *argumentParameter.MaxValue, fieldType)
+	}
+
+	return nil
+}
+
+// isAssignableArgument returns true if a field of the type is able to hold the converted value.
+// Numbers can be converted to any other number of the same kind, if the value fits.
+func isAssignableArgument(valueType, fieldType reflect.Type) bool {
+	if valueType.AssignableTo(fieldType) {
+		return true
+	}
+
+	switch valueType.Kind() {
+	case reflect.Int64:
+		return fieldType.Kind() >= reflect.Int && fieldType.Kind() <= reflect.Int64
+	case reflect.Float64:
+		return fieldType.Kind() == reflect.Float32 || fieldType.Kind() == reflect.Float64
+	default:
+		return false
+	}
+}
+
+// setupArgumentStruct derives the argument parameters of the command from its argument struct.
+func (ic *InteractionCommandable) setupArgumentStruct(path string) error {
+	if ic.ArgumentStruct == nil || ic.argumentStruct != nil {
+		return nil
+	}
+
+	if len(ic.ArgumentParameter) > 0 {
+		return newCommandValidationError(path, "argument_struct", "cannot be used with ArgumentParameter")
+	}
+
+	parsed, argumentParameters, err := parseArgumentStruct(ic.ArgumentStruct)
+	if err != nil {
+		return newCommandValidationError(path, "argument_struct", err.Error())
+	}
+
+	ic.argumentStruct = parsed
+	ic.ArgumentParameter = argumentParameters
+
+	return nil
+}
+
+// checkArgumentStructConverters checks the argument structs of the command and its subcommands
+// against the converters of the command tree.
+func (ic *InteractionCommandable) checkArgumentStructConverters(path string, converters *InteractionConverters) error {
+	if ic.argumentStruct != nil {
+		err := ic.argumentStruct.checkConverterTypes(ic.ArgumentParameter, converters)
+		if err != nil {
+			return newCommandValidationError(path, "argument_struct", err.Error())
+		}
+	}
+
+	for _, command := range ic.commands {
+		err := command.checkArgumentStructConverters(path+" "+command.Name, converters)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkConverterTypes checks the fields of arguments with custom converters are able to hold the
+// output of the converter. Built-in argument types are checked when the struct is parsed.
+func (parsed *argumentStruct) checkConverterTypes(argumentParameters []ArgumentParameter, converters *InteractionConverters) error {
+	for i, field := range parsed.fields {
+		argumentType := argumentParameters[i].ArgumentType
+		if _, ok := argumentGoTypes[argumentType]; ok {
+			continue
+		}
+
+		converter := converters.GetConverter(argumentType)
+		if converter == nil {
+			return fmt.Errorf("field %s: no converter is registered for argument type %d", parsed.structType.Field(field.index).Name, argumentType)
+		}
+
+		// The output of converters without a default value is not known.
+		if converter.outputType == nil {
+			continue
+		}
+
+		structField := parsed.structType.Field(field.index)
+
+		fieldType := structField.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		if !isAssignableArgument(converter.outputType, fieldType) && !converter.outputType.AssignableTo(structField.Type) {
+			return fmt.Errorf("field %s: %s is not able to hold a %s argument", structField.Name, structField.Type, converter.outputType)
+		}
+	}
+
+	return nil
+}
+
+// fillArgumentStruct creates a new argument struct from the parsed arguments and adds it to the context.
+func (ic *InteractionCommandable) fillArgumentStruct(ctx context.Context, arguments map[string]*Argument) (context.Context, error) {
+	if ic.argumentStruct == nil {
+		return ctx, nil
+	}
+
+	value := reflect.New(ic.argumentStruct.structType)
+
+	for _, field := range ic.argumentStruct.fields {
+		argument, ok := arguments[field.name]
+		if !ok || argument.value == nil {
+			continue
+		}
+
+		// Pointer fields are left nil when the argument is not provided.
+		if !argument.provided && value.Elem().Field(field.index).Kind() == reflect.Pointer {
+			continue
+		}
+
+		err := setArgumentField(value.Elem().Field(field.index), argument.value)
+		if err != nil {
+			return ctx, fmt.Errorf("argument %s: %w", field.name, err)
+		}
+	}
+
+	return AddArgumentStructToContext(ctx, value.Interface()), nil
+}
+
+func setArgumentField(field reflect.Value, value interface{}) error {
+	// Values that are already pointers, such as timezones, are set directly.
+	if reflect.TypeOf(value).AssignableTo(field.Type()) {
+		field.Set(reflect.ValueOf(value))
+
+		return nil
+	}
+
+	target := field
+
+	if field.Kind() == reflect.Pointer {
+		target = reflect.New(field.Type().Elem()).Elem()
+	}
+
+	reflectValue := reflect.ValueOf(value)
+
+	switch {
+	case reflectValue.Type().AssignableTo(target.Type()):
+		target.Set(reflectValue)
+	case isAssignableArgument(reflectValue.Type(), target.Type()):
+		if isArgumentOverflow(reflectValue, target) {
+			return ErrArgumentOverflow
+		}
+
+		target.Set(reflectValue.Convert(target.Type()))
+	default:
+		return ErrInvalidArgumentType
+	}
+
+	if field.Kind() == reflect.Pointer {
+		field.Set(target.Addr())
+	}
+
+	return nil
+}
+
+// isArgumentOverflow returns true if a number does not fit in the target.
+func isArgumentOverflow(value, target reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Int64:
+		return target.OverflowInt(value.Int())
+	case reflect.Float64:
+		return target.OverflowFloat(value.Float())
+	default:
+		return false
+	}
+}
+
+// GetArgumentStruct returns the argument struct of the command being invoked, filled with its arguments.
+// T must be the type of the ArgumentStruct of the command.
+func GetArgumentStruct[T any](ctx context.Context) (*T, error) {
+	value, ok := ctx.Value(ArgumentStructKey).(*T)
+	if !ok {
+		return nil, ErrInvalidArgumentType
+	}
+
+	return value, nil
+}
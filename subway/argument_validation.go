@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// Constraints of an argument parameter, used in ArgumentValidationError.
+const (
+	ArgumentConstraintMinValue     = "min_value"
+	ArgumentConstraintMaxValue     = "max_value"
+	ArgumentConstraintMinLength    = "min_length"
+	ArgumentConstraintMaxLength    = "max_length"
+	ArgumentConstraintChoices      = "choices"
+	ArgumentConstraintChannelTypes = "channel_types"
+)
+
+// validateArgument checks a converted argument meets the constraints of its parameter. Discord enforces
+// these constraints in the client, but they are not guaranteed for requests that did not come from it.
+func validateArgument(argumentParameter ArgumentParameter, rawOption discord.InteractionDataOption, value interface{}) error {
+	var err error
+
+	switch typedValue := value.(type) {
+	case int64:
+		err = validateArgumentValue(argumentParameter, float64(typedValue))
+	case float64:
+		err = validateArgumentValue(argumentParameter, typedValue)
+	case string:
+		err = validateArgumentLength(argumentParameter, typedValue)
+	case discord.Channel:
+		if len(argumentParameter.ChannelTypes) > 0 && !slices.Contains(argumentParameter.ChannelTypes, typedValue.Type) {
+			return newArgumentValidationError(argumentParameter, ArgumentConstraintChannelTypes,
+				fmt.Sprintf("does not include channel type %d", typedValue.Type))
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(argumentParameter.Choices) > 0 {
+		rawValue := rawOption.Value
+
+		// Arguments that were not sent as options, such as from components, have no raw value.
+		if len(rawValue) == 0 {
+			rawValue, err = json.Marshal(value)
+			if err != nil {
+				return fmt.Errorf("failed to marshal argument: %w", err)
+			}
+		}
+
+		if !isArgumentChoice(argumentParameter.Choices, rawValue, value) {
+			return newArgumentValidationError(argumentParameter, ArgumentConstraintChoices, "does not include the value")
+		}
+	}
+
+	return nil
+}
+
+func validateArgumentValue(argumentParameter ArgumentParameter, value float64) error {
+	minValue, maxValue := getArgumentBounds(argumentParameter)
+
+	if minValue != nil && value < *minValue {
+		return newArgumentValidationError(argumentParameter, ArgumentConstraintMinValue,
+			fmt.Sprintf("must be at least %s", strconv.FormatFloat(*minValue, 'f', -1, 64)))
+	}
+
+	if maxValue != nil && value > *maxValue {
+		return newArgumentValidationError(argumentParameter, ArgumentConstraintMaxValue,
+			fmt.Sprintf("must be at most %s", strconv.FormatFloat(*maxValue, 'f', -1, 64)))
+	}
+
+	return nil
+}
+
+// getArgumentBounds returns the bounds of a numeric argument. MinNumber and MaxNumber
+// are used over MinValue and MaxValue when set.
+func getArgumentBounds(argumentParameter ArgumentParameter) (minValue, maxValue *float64) {
+	minValue, maxValue = argumentParameter.MinNumber, argumentParameter.MaxNumber
+
+	if minValue == nil && argumentParameter.MinValue != nil {
+		bound := float64(*argumentParameter.MinValue)
+		minValue = &bound
+	}
+
+	if maxValue == nil && argumentParameter.MaxValue != nil {
+		bound := float64(*argumentParameter.MaxValue)
+		maxValue = &bound
+	}
+
+	return minValue, maxValue
+}
+
+func validateArgumentLength(argumentParameter ArgumentParameter, value string) error {
+	length := utf8.RuneCountInString(value)
+
+	if argumentParameter.MinLength != nil && length < int(*argumentParameter.MinLength) {
+		return newArgumentValidationError(argumentParameter, ArgumentConstraintMinLength,
+			fmt.Sprintf("must be at least %d characters", *argumentParameter.MinLength))
+	}
+
+	if argumentParameter.MaxLength != nil && length > int(*argumentParameter.MaxLength) {
+		return newArgumentValidationError(argumentParameter, ArgumentConstraintMaxLength,
+			fmt.Sprintf("must be at most %d characters", *argumentParameter.MaxLength))
+	}
+
+	return nil
+}
+
+// isArgumentChoice returns true if the value matches the value of any choice. Numbers are compared
+// by their value, so 2 and 2.0 are the same choice, otherwise the raw value is compared.
+func isArgumentChoice(choices []discord.ApplicationCommandOptionChoice, rawValue json.RawMessage, value interface{}) bool {
+	for _, choice := range choices {
+		var choiceNumber json.Number
+
+		switch typedValue := value.(type) {
+		case int64:
+			if json.Unmarshal(choice.Value, &choiceNumber) != nil {
+				continue
+			}
+
+			// Integers are compared exactly, as large integers lose precision as floats.
+			if choiceInt, err := choiceNumber.Int64(); err == nil {
+				if choiceInt == typedValue {
+					return true
+				}
+
+				continue
+			}
+
+			if choiceFloat, err := choiceNumber.Float64(); err == nil && choiceFloat == float64(typedValue) {
+				return true
+			}
+		case float64:
+			if json.Unmarshal(choice.Value, &choiceNumber) != nil {
+				continue
+			}
+
+			if choiceFloat, err := choiceNumber.Float64(); err == nil && choiceFloat == typedValue {
+				return true
+			}
+		default:
+			if equalJSON(choice.Value, rawValue) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func newArgumentValidationError(argumentParameter ArgumentParameter, constraint, reason string) *ArgumentValidationError {
+	return &ArgumentValidationError{
+		Argument:   argumentParameter.Name,
+		Constraint: constraint,
+		Reason:     reason,
+	}
+}
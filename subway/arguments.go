@@ -1,8 +1,13 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"image/color"
+	"mime"
+	"path"
+	"reflect"
+	"time"
 
 	"github.com/WelcomerTeam/Discord/discord"
 )
@@ -310,6 +315,285 @@ func (a *Argument) MustStrings() []string {
 	return value
 }
 
+// Attachment returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Attachment() (discord.MessageAttachment, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeAttachment) {
+		value, _ := a.value.(discord.MessageAttachment)
+
+		return value, nil
+	}
+
+	return discord.MessageAttachment{}, ErrInvalidArgumentType
+}
+
+// MustAttachment will attempt to do Attachment() and will panic if not possible.
+func (a *Argument) MustAttachment() discord.MessageAttachment {
+	value, err := a.Attachment()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Attachment(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// AttachmentURL returns the URL of an attachment argument.
+func (a *Argument) AttachmentURL() (string, error) {
+	value, err := a.Attachment()
+
+	return value.URL, err
+}
+
+// AttachmentFilename returns the filename of an attachment argument.
+func (a *Argument) AttachmentFilename() (string, error) {
+	value, err := a.Attachment()
+
+	return value.Filename, err
+}
+
+// AttachmentSize returns the size in bytes of an attachment argument.
+func (a *Argument) AttachmentSize() (int32, error) {
+	value, err := a.Attachment()
+
+	return value.Size, err
+}
+
+// AttachmentContentType returns the media type of an attachment argument. The resolved
+// attachment does not include the content type, so it is based on the file extension and
+// is empty if the extension is not known.
+func (a *Argument) AttachmentContentType() (string, error) {
+	value, err := a.Attachment()
+	if err != nil || value.Filename == "" {
+		return "", err
+	}
+
+	return mime.TypeByExtension(path.Ext(value.Filename)), nil
+}
+
+// Mentionable returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Mentionable() (Mentionable, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeMentionable) {
+		value, _ := a.value.(Mentionable)
+
+		return value, nil
+	}
+
+	return Mentionable{}, ErrInvalidArgumentType
+}
+
+// MustMentionable will attempt to do Mentionable() and will panic if not possible.
+func (a *Argument) MustMentionable() Mentionable {
+	value, err := a.Mentionable()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Mentionable(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Duration returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Duration() (time.Duration, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeDuration) {
+		value, _ := a.value.(time.Duration)
+
+		return value, nil
+	}
+
+	return 0, ErrInvalidArgumentType
+}
+
+// MustDuration will attempt to do Duration() and will panic if not possible.
+func (a *Argument) MustDuration() time.Duration {
+	value, err := a.Duration()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Duration(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Timestamp returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Timestamp() (time.Time, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeTimestamp) {
+		value, _ := a.value.(time.Time)
+
+		return value, nil
+	}
+
+	return time.Time{}, ErrInvalidArgumentType
+}
+
+// MustTimestamp will attempt to do Timestamp() and will panic if not possible.
+func (a *Argument) MustTimestamp() time.Time {
+	value, err := a.Timestamp()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Timestamp(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Timezone returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+// If the argument was not provided, the location is nil.
+func (a *Argument) Timezone() (*time.Location, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeTimezone) {
+		value, _ := a.value.(*time.Location)
+
+		return value, nil
+	}
+
+	return nil, ErrInvalidArgumentType
+}
+
+// MustTimezone will attempt to do Timezone() and will panic if not possible.
+func (a *Argument) MustTimezone() *time.Location {
+	value, err := a.Timezone()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Timezone(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Message returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Message() (discord.Message, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeMessage) {
+		value, _ := a.value.(discord.Message)
+
+		return value, nil
+	}
+
+	return discord.Message{}, ErrInvalidArgumentType
+}
+
+// MustMessage will attempt to do Message() and will panic if not possible.
+func (a *Argument) MustMessage() discord.Message {
+	value, err := a.Message()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Message(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Invite returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Invite() (discord.Invite, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeInvite) {
+		value, _ := a.value.(discord.Invite)
+
+		return value, nil
+	}
+
+	return discord.Invite{}, ErrInvalidArgumentType
+}
+
+// MustInvite will attempt to do Invite() and will panic if not possible.
+func (a *Argument) MustInvite() discord.Invite {
+	value, err := a.Invite()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Invite(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// Webhook returns an argument as the specified Type.
+// If the argument is not the right type for the converter
+// that made the argument, ErrInvalidArgumentType will be returned.
+func (a *Argument) Webhook() (discord.Webhook, error) {
+	if argumentTypeIs(a.ArgumentType, ArgumentTypeWebhook) {
+		value, _ := a.value.(discord.Webhook)
+
+		return value, nil
+	}
+
+	return discord.Webhook{}, ErrInvalidArgumentType
+}
+
+// MustWebhook will attempt to do Webhook() and will panic if not possible.
+func (a *Argument) MustWebhook() discord.Webhook {
+	value, err := a.Webhook()
+	if err != nil {
+		panic(fmt.Sprintf(`argument: Webhook(): %v`, err.Error()))
+	}
+
+	return value
+}
+
+// GetArgumentAs returns the value of an argument as T. T can be the type the converter outputs,
+// a pointer to it, or for numbers, any number of the same kind. Arguments that were not provided
+// return their default. If there is no value, ErrArgumentNotFound is returned. If the value is
+// not able to be T, ErrInvalidArgumentType is returned.
+func GetArgumentAs[T any](ctx context.Context, name string) (T, error) {
+	var value T
+
+	argument, ok := GetArgumentsFromContext(ctx)[name]
+	if !ok || argument.value == nil {
+		return value, ErrArgumentNotFound
+	}
+
+	return ArgumentAs[T](argument)
+}
+
+// MustGetArgumentAs will attempt to do GetArgumentAs() and will panic if not possible.
+func MustGetArgumentAs[T any](ctx context.Context, name string) T {
+	value, err := GetArgumentAs[T](ctx, name)
+	if err != nil {
+		panic(fmt.Sprintf(`argument: GetArgumentAs(%s): %v`, name, err.Error()))
+	}
+
+	return value
+}
+
+// GetOptionalArgument returns the value of an argument as T and if it was provided.
+// Arguments that were not provided return their default and false instead of an error.
+func GetOptionalArgument[T any](ctx context.Context, name string) (T, bool, error) {
+	var value T
+
+	argument, ok := GetArgumentsFromContext(ctx)[name]
+	if !ok || argument.value == nil {
+		return value, false, nil
+	}
+
+	value, err := ArgumentAs[T](argument)
+	if err != nil {
+		return value, false, err
+	}
+
+	return value, argument.provided, nil
+}
+
+// ArgumentAs returns the value of the argument as T.
+// If the value is not able to be T, ErrInvalidArgumentType will be returned.
+func ArgumentAs[T any](a *Argument) (T, error) {
+	var value T
+
+	if typedValue, ok := a.value.(T); ok {
+		return typedValue, nil
+	}
+
+	if a.value == nil {
+		return value, ErrInvalidArgumentType
+	}
+
+	err := setArgumentField(reflect.ValueOf(&value).Elem(), a.value)
+
+	return value, err
+}
+
 func argumentTypeIs(argumentType ArgumentType, argumentTypes ...ArgumentType) bool {
 	for _, aType := range argumentTypes {
 		if argumentType == aType {
@@ -3,8 +3,14 @@ package internal
 import (
 	"crypto/ed25519"
 	"encoding/hex"
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	discord "github.com/WelcomerTeam/Discord/discord"
 	gotils "github.com/savsgio/gotils/strconv"
 )
 
@@ -13,23 +19,84 @@ const (
 	HeaderTimestamp = "X-Signature-Timestamp"
 )
 
-func (sub *Subway) verifySignature(request *http.Request, body []byte) bool {
+// Reasons a request signature can be rejected, used as prometheus labels.
+const (
+	signatureRejectionInvalidSignature = "invalid_signature"
+	signatureRejectionInvalidTimestamp = "invalid_timestamp"
+	signatureRejectionStaleTimestamp   = "stale_timestamp"
+	signatureRejectionFutureTimestamp  = "future_timestamp"
+	signatureRejectionMismatch         = "mismatch"
+)
+
+func (sub *Subway) verifySignature(request *http.Request, body []byte) error {
 	sig, ok := verifyEd25519Header(request.Header.Get(HeaderSignature))
 	if !ok {
-		return false
+		subwaySignatureRejectedTotal.WithLabelValues(signatureRejectionInvalidSignature).Add(1)
+
+		return ErrInvalidRequestSignature
 	}
 
 	timestamp := request.Header.Get(HeaderTimestamp)
 
+	if sub.maximumTimestampSkew > 0 {
+		err := sub.verifyTimestamp(timestamp)
+		if err != nil {
+			return err
+		}
+	}
+
 	message := append(gotils.S2B(timestamp), body...)
 
-	for _, key := range sub.publicKeys {
-		if ed25519.Verify(key, message, sig) {
-			return true
+	if sub.getPublicKeys(body).verify(message, sig) {
+		return nil
+	}
+
+	subwaySignatureRejectedTotal.WithLabelValues(signatureRejectionMismatch).Add(1)
+
+	return ErrInvalidRequestSignature
+}
+
+// getPublicKeys returns the public keys of the application the request is routed to. Requests
+// for applications that are not registered use the subway public keys. The application ID is
+// read before the signature is verified, but it is part of the signed body, so a request signed
+// by one application cannot be handled as another.
+func (sub *Subway) getPublicKeys(body []byte) *publicKeySet {
+	var request struct {
+		ApplicationID discord.Snowflake `json:"application_id"`
+	}
+
+	if json.Unmarshal(body, &request) == nil && !request.ApplicationID.IsNil() {
+		if application := sub.GetApplication(request.ApplicationID); application != nil {
+			return &application.publicKeys
 		}
 	}
 
-	return false
+	return &sub.publicKeys
+}
+
+// verifyTimestamp checks the signature timestamp is within the maximum timestamp skew.
+func (sub *Subway) verifyTimestamp(value string) error {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		subwaySignatureRejectedTotal.WithLabelValues(signatureRejectionInvalidTimestamp).Add(1)
+
+		return ErrInvalidSignatureTimestamp
+	}
+
+	skew := time.Since(time.Unix(seconds, 0))
+
+	switch {
+	case skew > sub.maximumTimestampSkew:
+		subwaySignatureRejectedTotal.WithLabelValues(signatureRejectionStaleTimestamp).Add(1)
+
+		return ErrStaleSignatureTimestamp
+	case skew < -sub.maximumTimestampSkew:
+		subwaySignatureRejectedTotal.WithLabelValues(signatureRejectionFutureTimestamp).Add(1)
+
+		return ErrFutureSignatureTimestamp
+	}
+
+	return nil
 }
 
 func verifyEd25519Header(value string) ([]byte, bool) {
@@ -48,3 +115,114 @@ func verifyEd25519Header(value string) ([]byte, bool) {
 
 	return sig, true
 }
+
+// publicKeySet is a set of public keys that can be changed at runtime.
+type publicKeySet struct {
+	keysMu sync.RWMutex
+	keys   []ed25519.PublicKey
+}
+
+// verify returns true if any key in the set signed the message.
+func (pks *publicKeySet) verify(message, sig []byte) bool {
+	pks.keysMu.RLock()
+	defer pks.keysMu.RUnlock()
+
+	for _, key := range pks.keys {
+		if ed25519.Verify(key, message, sig) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (pks *publicKeySet) set(publicKeys string) error {
+	keys := make([]ed25519.PublicKey, 0)
+
+	for _, publicKey := range strings.Split(publicKeys, ",") {
+		publicKey = strings.TrimSpace(publicKey)
+		if publicKey == "" {
+			continue
+		}
+
+		key, err := parsePublicKey(publicKey)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+	}
+
+	pks.keysMu.Lock()
+	pks.keys = keys
+	pks.keysMu.Unlock()
+
+	return nil
+}
+
+func (pks *publicKeySet) add(publicKey string) error {
+	key, err := parsePublicKey(strings.TrimSpace(publicKey))
+	if err != nil {
+		return err
+	}
+
+	pks.keysMu.Lock()
+	defer pks.keysMu.Unlock()
+
+	for _, existing := range pks.keys {
+		if existing.Equal(key) {
+			return nil
+		}
+	}
+
+	pks.keys = append(pks.keys, key)
+
+	return nil
+}
+
+func (pks *publicKeySet) remove(publicKey string) (bool, error) {
+	key, err := parsePublicKey(strings.TrimSpace(publicKey))
+	if err != nil {
+		return false, err
+	}
+
+	pks.keysMu.Lock()
+	defer pks.keysMu.Unlock()
+
+	for i, existing := range pks.keys {
+		if existing.Equal(key) {
+			pks.keys = append(pks.keys[:i:i], pks.keys[i+1:]...)
+
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// SetPublicKeys replaces all public keys used for signature validation.
+// Public keys are hex encoded and comma delimited.
+func (sub *Subway) SetPublicKeys(publicKeys string) error {
+	return sub.publicKeys.set(publicKeys)
+}
+
+// AddPublicKey adds a hex encoded public key used for signature validation.
+// Adding a key that already exists does nothing.
+func (sub *Subway) AddPublicKey(publicKey string) error {
+	return sub.publicKeys.add(publicKey)
+}
+
+// RemovePublicKey retires a hex encoded public key, so it is no longer used for
+// signature validation. Returns false if the key was not present.
+func (sub *Subway) RemovePublicKey(publicKey string) (bool, error) {
+	return sub.publicKeys.remove(publicKey)
+}
+
+func parsePublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, ErrInvalidPublicKey
+	}
+
+	return ed25519.PublicKey(key), nil
+}
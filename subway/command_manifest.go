@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// CommandManifest is a canonical representation of application commands. Commands and
+// subcommands are ordered by name and fields set by discord are removed, so the hash only
+// changes when the commands change.
+type CommandManifest struct {
+	Commands []discord.ApplicationCommand `json:"commands"`
+	Hash     string                       `json:"hash"`
+}
+
+// CommandDriftCheckOptions represents the options to check for command drift at startup.
+type CommandDriftCheckOptions struct {
+	// Token and application ID used to check the commands registered directly to subway.
+	// If empty, only registered applications are checked. Token must have "Bot " added.
+	Token         string
+	ApplicationID discord.Snowflake
+}
+
+// CommandDrift is the result of comparing the commands of a scope to the commands registered with discord.
+type CommandDrift struct {
+	// GuildID the commands are registered in. Empty for global commands.
+	GuildID discord.Snowflake `json:"guild_id,omitempty"`
+
+	ExpectedHash string `json:"expected_hash"`
+	LiveHash     string `json:"live_hash"`
+	Drifted      bool   `json:"drifted"`
+}
+
+// NewCommandManifest creates a manifest of the commands passed.
+func NewCommandManifest(applicationCommands []discord.ApplicationCommand) (*CommandManifest, error) {
+	commands := make([]discord.ApplicationCommand, 0, len(applicationCommands))
+
+	for _, applicationCommand := range applicationCommands {
+		commands = append(commands, canonicalApplicationCommand(applicationCommand))
+	}
+
+	slices.SortFunc(commands, func(a, b discord.ApplicationCommand) int {
+		return cmp.Or(
+			cmp.Compare(getApplicationCommandType(a), getApplicationCommandType(b)),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+
+	// encoding/json orders map keys, so localizations are also deterministic.
+	manifestJSON, err := json.Marshal(commands)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal commands: %w", err)
+	}
+
+	hash := sha256.Sum256(manifestJSON)
+
+	return &CommandManifest{
+		Commands: commands,
+		Hash:     hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// Manifest creates a manifest of the command tree.
+func (ic *InteractionCommandable) Manifest() (*CommandManifest, error) {
+	return NewCommandManifest(ic.MapApplicationCommands())
+}
+
+// CheckCommandDrift compares the commands registered directly to subway with the commands registered with discord.
+// Token must have "Bot " added.
+func (sub *Subway) CheckCommandDrift(ctx context.Context, token string, applicationID discord.Snowflake) ([]CommandDrift, error) {
+	session := discord.NewSession(token, sub.RESTInterface)
+
+	scopedCommands := sub.addStaleCommandGuilds(applicationID, sub.Commands.MapScopedApplicationCommands(sub.DevelopmentGuildID))
+
+	return checkCommandDrift(ctx, session, applicationID, scopedCommands)
+}
+
+// CheckCommandDrift compares the application commands with the commands registered with discord.
+func (application *Application) CheckCommandDrift(ctx context.Context) ([]CommandDrift, error) {
+	if application.token == "" {
+		return nil, ErrMissingApplicationToken
+	}
+
+	session := discord.NewSession(application.token, application.RESTInterface)
+
+	scopedCommands := application.subway.addStaleCommandGuilds(application.ID, application.Commands.MapScopedApplicationCommands(application.DevelopmentGuildID))
+
+	return checkCommandDrift(ctx, session, application.ID, scopedCommands)
+}
+
+func checkCommandDrift(ctx context.Context, session *discord.Session, applicationID discord.Snowflake, scopedCommands ScopedApplicationCommands) ([]CommandDrift, error) {
+	liveCommands, err := discord.GetGlobalApplicationCommands(ctx, session, applicationID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commands: %w", err)
+	}
+
+	globalDrift, err := compareCommandManifests(0, scopedCommands.Global, liveCommands)
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := []CommandDrift{globalDrift}
+
+	guildIDs := make([]discord.Snowflake, 0, len(scopedCommands.Guilds))
+
+	for guildID := range scopedCommands.Guilds {
+		guildIDs = append(guildIDs, guildID)
+	}
+
+	slices.Sort(guildIDs)
+
+	for _, guildID := range guildIDs {
+		liveCommands, err = discord.GetGuildApplicationCommands(ctx, session, applicationID, guildID)
+		if err != nil {
+			return drifts, fmt.Errorf("failed to get commands for guild %d: %w", guildID, err)
+		}
+
+		guildDrift, err := compareCommandManifests(guildID, scopedCommands.Guilds[guildID], liveCommands)
+		if err != nil {
+			return drifts, err
+		}
+
+		drifts = append(drifts, guildDrift)
+	}
+
+	return drifts, nil
+}
+
+func compareCommandManifests(guildID discord.Snowflake, expectedCommands, liveCommands []discord.ApplicationCommand) (CommandDrift, error) {
+	expected, err := NewCommandManifest(expectedCommands)
+	if err != nil {
+		return CommandDrift{}, err
+	}
+
+	live, err := NewCommandManifest(liveCommands)
+	if err != nil {
+		return CommandDrift{}, err
+	}
+
+	return CommandDrift{
+		GuildID:      guildID,
+		ExpectedHash: expected.Hash,
+		LiveHash:     live.Hash,
+		Drifted:      expected.Hash != live.Hash,
+	}, nil
+}
+
+// checkCommandDriftOnStartup checks for command drift of subway and every registered
+// application, logging and recording the drift in prometheus.
+func (sub *Subway) checkCommandDriftOnStartup() {
+	if sub.commandDriftCheck == nil {
+		return
+	}
+
+	if sub.commandDriftCheck.Token != "" && !sub.commandDriftCheck.ApplicationID.IsNil() {
+		drifts, err := sub.CheckCommandDrift(sub.Context, sub.commandDriftCheck.Token, sub.commandDriftCheck.ApplicationID)
+		sub.recordCommandDrift(sub.commandDriftCheck.ApplicationID, drifts, err)
+	}
+
+	for _, application := range sub.GetApplications() {
+		if application.token == "" {
+			continue
+		}
+
+		drifts, err := application.CheckCommandDrift(sub.Context)
+		sub.recordCommandDrift(application.ID, drifts, err)
+	}
+}
+
+func (sub *Subway) recordCommandDrift(applicationID discord.Snowflake, drifts []CommandDrift, err error) {
+	if err != nil {
+		sub.Logger.Warn().Err(err).Int64("application_id", int64(applicationID)).Msg("Failed to check command drift")
+
+		return
+	}
+
+	for _, drift := range drifts {
+		var guildID string
+
+		if !drift.GuildID.IsNil() {
+			guildID = strconv.FormatInt(int64(drift.GuildID), 10)
+		}
+
+		if drift.Drifted {
+			sub.Logger.Warn().
+				Int64("application_id", int64(applicationID)).
+				Str("guild_id", guildID).
+				Str("expected_hash", drift.ExpectedHash).
+				Str("live_hash", drift.LiveHash).
+				Msg("Registered commands differ from the command tree, commands may need to be synced")
+
+			subwayCommandDrift.WithLabelValues(strconv.FormatInt(int64(applicationID), 10), guildID).Set(1)
+		} else {
+			subwayCommandDrift.WithLabelValues(strconv.FormatInt(int64(applicationID), 10), guildID).Set(0)
+		}
+	}
+}
+
+// canonicalApplicationCommand removes fields that are set by discord and applies defaults,
+// so commands from the command tree and from discord are able to be compared.
+func canonicalApplicationCommand(command discord.ApplicationCommand) discord.ApplicationCommand {
+	commandType := getApplicationCommandType(command)
+
+	canonical := discord.ApplicationCommand{
+		Type:                     &commandType,
+		Name:                     command.Name,
+		NameLocalizations:        command.NameLocalizations,
+		Description:              command.Description,
+		DescriptionLocalizations: command.DescriptionLocalizations,
+		DefaultMemberPermission:  command.DefaultMemberPermission,
+		Options:                  canonicalApplicationCommandOptions(command.Options),
+	}
+
+	// Commands are usable in DMs unless specified.
+	if !derefOr(command.DMPermission, true) {
+		canonical.DMPermission = command.DMPermission
+	}
+
+	return canonical
+}
+
+func canonicalApplicationCommandOptions(options []discord.ApplicationCommandOption) []discord.ApplicationCommandOption {
+	if len(options) == 0 {
+		return nil
+	}
+
+	canonicalOptions := make([]discord.ApplicationCommandOption, 0, len(options))
+
+	for _, option := range options {
+		canonicalOption := option
+		canonicalOption.Options = canonicalApplicationCommandOptions(option.Options)
+		canonicalOption.ChannelTypes = slices.Sorted(slices.Values(option.ChannelTypes))
+
+		if !derefOr(option.Autocomplete, false) {
+			canonicalOption.Autocomplete = nil
+		}
+
+		if len(option.Choices) > 0 {
+			canonicalOption.Choices = make([]discord.ApplicationCommandOptionChoice, 0, len(option.Choices))
+
+			for _, choice := range option.Choices {
+				var compactValue bytes.Buffer
+
+				if json.Compact(&compactValue, choice.Value) == nil {
+					choice.Value = compactValue.Bytes()
+				}
+
+				canonicalOption.Choices = append(canonicalOption.Choices, choice)
+			}
+		}
+
+		canonicalOptions = append(canonicalOptions, canonicalOption)
+	}
+
+	// The order of arguments is significant, but the order of subcommands is not.
+	if len(getArgumentOptionNames(canonicalOptions)) == 0 {
+		slices.SortFunc(canonicalOptions, func(a, b discord.ApplicationCommandOption) int {
+			return cmp.Compare(a.Name, b.Name)
+		})
+	}
+
+	return canonicalOptions
+}
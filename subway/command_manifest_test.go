@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+var testLocales = []string{"da", "de", "es-ES", "fr", "it", "ja", "ko", "nl", "pl", "pt-BR", "ru", "sv-SE", "tr", "uk"}
+
+// newTestLocalizations creates localizations for every test locale, inserted in the order passed.
+func newTestLocalizations(value string, locales []string) map[string]string {
+	localizations := make(map[string]string, len(locales))
+
+	for _, locale := range locales {
+		localizations[locale] = value + " " + locale
+	}
+
+	return localizations
+}
+
+func newTestManifestCommands(locales []string, reverse bool) []discord.ApplicationCommand {
+	ban := newTestApplicationCommand("ban",
+		newTestApplicationCommandOption("user", discord.ApplicationCommandOptionTypeUser),
+		newTestApplicationCommandOption("reason", discord.ApplicationCommandOptionTypeString),
+	)
+	ban.NameLocalizations = newTestLocalizations("ban", locales)
+	ban.DescriptionLocalizations = newTestLocalizations("ban a user", locales)
+	ban.Options[0].DescriptionLocalizations = newTestLocalizations("user", locales)
+
+	add := newTestApplicationCommandOption("add", discord.ApplicationCommandOptionTypeSubCommand)
+	remove := newTestApplicationCommandOption("remove", discord.ApplicationCommandOptionTypeSubCommand)
+
+	role := newTestApplicationCommand("role", add, remove)
+	if reverse {
+		role.Options = []discord.ApplicationCommandOption{remove, add}
+	}
+
+	if reverse {
+		return []discord.ApplicationCommand{role, ban}
+	}
+
+	return []discord.ApplicationCommand{ban, role}
+}
+
+func TestNewCommandManifest(t *testing.T) {
+	expected, err := NewCommandManifest(newTestManifestCommands(testLocales, false))
+	if err != nil {
+		t.Fatalf("NewCommandManifest() error = %v", err)
+	}
+
+	reversedLocales := make([]string, 0, len(testLocales))
+	for i := len(testLocales) - 1; i >= 0; i-- {
+		reversedLocales = append(reversedLocales, testLocales[i])
+	}
+
+	discordCommands := newTestManifestCommands(testLocales, false)
+	for i := range discordCommands {
+		applicationID := discord.Snowflake(2)
+
+		discordCommands[i].ApplicationID = &applicationID
+		discordCommands[i].Version = 3
+	}
+
+	changedCommands := newTestManifestCommands(testLocales, false)
+	changedCommands[0].DescriptionLocalizations["fr"] = "changed"
+
+	reorderedArguments := newTestManifestCommands(testLocales, false)
+	reorderedArguments[0].Options[0], reorderedArguments[0].Options[1] = reorderedArguments[0].Options[1], reorderedArguments[0].Options[0]
+
+	tests := []struct {
+		name     string
+		commands []discord.ApplicationCommand
+		same     bool
+	}{
+		{name: "localizations inserted in another order", commands: newTestManifestCommands(reversedLocales, false), same: true},
+		{name: "commands and subcommands in another order", commands: newTestManifestCommands(testLocales, true), same: true},
+		{name: "fields set by discord", commands: discordCommands, same: true},
+		{name: "changed localization", commands: changedCommands, same: false},
+		{name: "reordered arguments", commands: reorderedArguments, same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Maps are iterated in a random order, so repeat to catch an unstable hash.
+			for range 20 {
+				manifest, err := NewCommandManifest(tt.commands)
+				if err != nil {
+					t.Fatalf("NewCommandManifest() error = %v", err)
+				}
+
+				if same := manifest.Hash == expected.Hash; same != tt.same {
+					t.Fatalf("Hash = %s, expected %s, same = %t, want %t", manifest.Hash, expected.Hash, same, tt.same)
+				}
+			}
+		})
+	}
+}
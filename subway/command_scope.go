@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// CommandScope is where a command is registered when syncing.
+type CommandScope struct {
+	// GuildIDs the command is registered in. The command is registered globally if empty.
+	GuildIDs []discord.Snowflake
+
+	// Development commands are registered in the development guild instead of globally.
+	Development bool
+}
+
+// GlobalScope registers a command globally.
+func GlobalScope() *CommandScope {
+	return &CommandScope{}
+}
+
+// GuildScope registers a command in only the provided guilds.
+func GuildScope(guildIDs ...discord.Snowflake) *CommandScope {
+	return &CommandScope{GuildIDs: guildIDs}
+}
+
+// DevelopmentScope registers a command in the development guild.
+func DevelopmentScope() *CommandScope {
+	return &CommandScope{Development: true}
+}
+
+// ScopedApplicationCommands are application commands grouped by where they are registered.
+type ScopedApplicationCommands struct {
+	Global []discord.ApplicationCommand
+	Guilds map[discord.Snowflake][]discord.ApplicationCommand
+}
+
+// MapScopedApplicationCommands maps commands by their scope. Development commands are registered in the
+// development guild, which is always included so commands removed from it are cleared. If there is no
+// development guild, development commands are not included.
+func (ic *InteractionCommandable) MapScopedApplicationCommands(developmentGuildID discord.Snowflake) ScopedApplicationCommands {
+	scopedCommands := ScopedApplicationCommands{
+		Global: make([]discord.ApplicationCommand, 0, len(ic.commands)),
+		Guilds: make(map[discord.Snowflake][]discord.ApplicationCommand),
+	}
+
+	if !developmentGuildID.IsNil() {
+		scopedCommands.Guilds[developmentGuildID] = make([]discord.ApplicationCommand, 0)
+	}
+
+	for _, interactionCommandable := range ic.getSortedCommands() {
+		applicationCommand := interactionCommandable.mapApplicationCommand()
+
+		scope := interactionCommandable.Scope
+
+		switch {
+		case scope == nil || (!scope.Development && len(scope.GuildIDs) == 0):
+			scopedCommands.Global = append(scopedCommands.Global, applicationCommand)
+		case scope.Development:
+			if !developmentGuildID.IsNil() {
+				scopedCommands.Guilds[developmentGuildID] = append(scopedCommands.Guilds[developmentGuildID], applicationCommand)
+			}
+		default:
+			for _, guildID := range scope.GuildIDs {
+				scopedCommands.Guilds[guildID] = append(scopedCommands.Guilds[guildID], applicationCommand)
+			}
+		}
+	}
+
+	return scopedCommands
+}
+
+// addStaleCommandGuilds adds the guilds that commands have previously been synced to, but no
+// longer have any commands, with no commands so they are cleared.
+func (sub *Subway) addStaleCommandGuilds(applicationID discord.Snowflake, scopedCommands ScopedApplicationCommands) ScopedApplicationCommands {
+	sub.syncedCommandGuildsMu.Lock()
+	defer sub.syncedCommandGuildsMu.Unlock()
+
+	for guildID := range sub.syncedCommandGuilds[applicationID] {
+		if _, ok := scopedCommands.Guilds[guildID]; !ok {
+			scopedCommands.Guilds[guildID] = make([]discord.ApplicationCommand, 0)
+		}
+	}
+
+	for _, guildID := range sub.commandGuildIDs {
+		if _, ok := scopedCommands.Guilds[guildID]; !ok {
+			scopedCommands.Guilds[guildID] = make([]discord.ApplicationCommand, 0)
+		}
+	}
+
+	return scopedCommands
+}
+
+// recordSyncedCommandGuilds records the guilds that have commands after syncing.
+func (sub *Subway) recordSyncedCommandGuilds(applicationID discord.Snowflake, scopedCommands ScopedApplicationCommands) {
+	sub.syncedCommandGuildsMu.Lock()
+	defer sub.syncedCommandGuildsMu.Unlock()
+
+	if sub.syncedCommandGuilds == nil {
+		sub.syncedCommandGuilds = make(map[discord.Snowflake]map[discord.Snowflake]bool)
+	}
+
+	guildIDs := make(map[discord.Snowflake]bool, len(scopedCommands.Guilds))
+
+	for guildID, applicationCommands := range scopedCommands.Guilds {
+		if len(applicationCommands) > 0 {
+			guildIDs[guildID] = true
+		}
+	}
+
+	sub.syncedCommandGuilds[applicationID] = guildIDs
+}
+
+// syncScopedApplicationCommands overwrites the global commands and the commands of each guild.
+// Guilds with no commands are cleared.
+func syncScopedApplicationCommands(ctx context.Context, session *discord.Session, applicationID discord.Snowflake, scopedCommands ScopedApplicationCommands) error {
+	_, err := discord.BulkOverwriteGlobalApplicationCommands(ctx, session, applicationID, scopedCommands.Global)
+	if err != nil {
+		return fmt.Errorf("failed to bulk overwrite commands: %w", err)
+	}
+
+	for guildID, applicationCommands := range scopedCommands.Guilds {
+		_, err = discord.BulkOverwriteGuildApplicationCommands(ctx, session, applicationID, guildID, applicationCommands)
+		if err != nil {
+			return fmt.Errorf("failed to bulk overwrite commands for guild %d: %w", guildID, err)
+		}
+	}
+
+	return nil
+}
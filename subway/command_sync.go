@@ -0,0 +1,421 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// CommandChange is a single command that differs between discord and the command tree.
+type CommandChange struct {
+	Name string                         `json:"name"`
+	Type discord.ApplicationCommandType `json:"type"`
+
+	// ID of the command registered with discord. Empty for added commands.
+	ID discord.Snowflake `json:"id,omitempty"`
+
+	// GuildID the command is registered in. Empty for global commands.
+	GuildID discord.Snowflake `json:"guild_id,omitempty"`
+
+	// Fields that have changed, such as "description" or "options.user.required".
+	Fields []string `json:"fields,omitempty"`
+
+	// Command is the command as it is in the command tree. Empty for removed commands.
+	Command discord.ApplicationCommand `json:"-"`
+}
+
+// CommandDiff is the difference between the commands registered with discord and the command tree.
+type CommandDiff struct {
+	Added     []CommandChange `json:"added"`
+	Removed   []CommandChange `json:"removed"`
+	Changed   []CommandChange `json:"changed"`
+	Unchanged int             `json:"unchanged"`
+}
+
+// HasChanges returns true if any commands need to be created, edited or removed.
+func (diff *CommandDiff) HasChanges() bool {
+	return len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Changed) > 0
+}
+
+// DiffApplicationCommands computes the changes needed for the current commands to match the desired commands.
+// Commands are matched by their name and type.
+func DiffApplicationCommands(current, desired []discord.ApplicationCommand) *CommandDiff {
+	diff := &CommandDiff{
+		Added:   make([]CommandChange, 0),
+		Removed: make([]CommandChange, 0),
+		Changed: make([]CommandChange, 0),
+	}
+
+	currentCommands := make(map[string]discord.ApplicationCommand, len(current))
+
+	for _, command := range current {
+		currentCommands[getApplicationCommandKey(command)] = command
+	}
+
+	desiredCommands := make(map[string]bool, len(desired))
+
+	for _, command := range desired {
+		key := getApplicationCommandKey(command)
+		desiredCommands[key] = true
+
+		currentCommand, ok := currentCommands[key]
+		if !ok {
+			diff.Added = append(diff.Added, CommandChange{
+				Name:    command.Name,
+				Type:    getApplicationCommandType(command),
+				Command: command,
+			})
+
+			continue
+		}
+
+		fields := diffApplicationCommand(currentCommand, command)
+		if len(fields) == 0 {
+			diff.Unchanged++
+
+			continue
+		}
+
+		diff.Changed = append(diff.Changed, CommandChange{
+			Name:    command.Name,
+			Type:    getApplicationCommandType(command),
+			ID:      getApplicationCommandID(currentCommand),
+			Fields:  fields,
+			Command: command,
+		})
+	}
+
+	for _, command := range current {
+		if !desiredCommands[getApplicationCommandKey(command)] {
+			diff.Removed = append(diff.Removed, CommandChange{
+				Name: command.Name,
+				Type: getApplicationCommandType(command),
+				ID:   getApplicationCommandID(command),
+			})
+		}
+	}
+
+	return diff
+}
+
+// SyncCommandsDiff syncs commands registered directly to subway by only creating, editing and
+// removing the commands that have changed. If dryRun is true, the diff is returned without
+// being applied. Token must have "Bot " added.
+func (sub *Subway) SyncCommandsDiff(ctx context.Context, token string, applicationID discord.Snowflake, dryRun bool) (*CommandDiff, error) {
+	session := discord.NewSession(token, sub.RESTInterface)
+
+	scopedCommands := sub.addStaleCommandGuilds(applicationID, sub.Commands.MapScopedApplicationCommands(sub.DevelopmentGuildID))
+
+	diff, err := syncApplicationCommandsDiff(ctx, session, applicationID, scopedCommands, dryRun)
+	if err == nil && !dryRun {
+		sub.recordSyncedCommandGuilds(applicationID, scopedCommands)
+	}
+
+	return diff, err
+}
+
+// SyncCommandsDiff syncs the application commands by only creating, editing and removing the
+// commands that have changed. If dryRun is true, the diff is returned without being applied.
+func (application *Application) SyncCommandsDiff(ctx context.Context, dryRun bool) (*CommandDiff, error) {
+	if application.token == "" {
+		return nil, ErrMissingApplicationToken
+	}
+
+	session := discord.NewSession(application.token, application.RESTInterface)
+
+	scopedCommands := application.subway.addStaleCommandGuilds(application.ID, application.Commands.MapScopedApplicationCommands(application.DevelopmentGuildID))
+
+	diff, err := syncApplicationCommandsDiff(ctx, session, application.ID, scopedCommands, dryRun)
+	if err == nil && !dryRun {
+		application.subway.recordSyncedCommandGuilds(application.ID, scopedCommands)
+	}
+
+	return diff, err
+}
+
+// syncApplicationCommandsDiff computes the diff of the global commands and the commands of each guild, then applies it.
+func syncApplicationCommandsDiff(ctx context.Context, session *discord.Session, applicationID discord.Snowflake, scopedCommands ScopedApplicationCommands, dryRun bool) (*CommandDiff, error) {
+	current, err := discord.GetGlobalApplicationCommands(ctx, session, applicationID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commands: %w", err)
+	}
+
+	diff := DiffApplicationCommands(current, scopedCommands.Global)
+
+	for guildID, desired := range scopedCommands.Guilds {
+		current, err = discord.GetGuildApplicationCommands(ctx, session, applicationID, guildID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get commands for guild %d: %w", guildID, err)
+		}
+
+		diff.merge(DiffApplicationCommands(current, desired), guildID)
+	}
+
+	if dryRun {
+		return diff, nil
+	}
+
+	for _, change := range diff.Added {
+		if change.GuildID.IsNil() {
+			_, err = discord.CreateGlobalApplicationCommand(ctx, session, applicationID, change.Command)
+		} else {
+			_, err = discord.CreateGuildApplicationCommand(ctx, session, applicationID, change.GuildID, change.Command)
+		}
+
+		if err != nil {
+			return diff, fmt.Errorf("failed to create command %s: %w", change.Name, err)
+		}
+	}
+
+	for _, change := range diff.Changed {
+		if change.GuildID.IsNil() {
+			_, err = discord.EditGlobalApplicationCommand(ctx, session, applicationID, change.ID, change.Command)
+		} else {
+			_, err = discord.EditGuildApplicationCommand(ctx, session, applicationID, change.GuildID, change.ID, change.Command)
+		}
+
+		if err != nil {
+			return diff, fmt.Errorf("failed to edit command %s: %w", change.Name, err)
+		}
+	}
+
+	for _, change := range diff.Removed {
+		if change.GuildID.IsNil() {
+			err = discord.DeleteGlobalApplicationCommand(ctx, session, applicationID, change.ID)
+		} else {
+			err = discord.DeleteGuildApplicationCommand(ctx, session, applicationID, change.GuildID, change.ID)
+		}
+
+		if err != nil {
+			return diff, fmt.Errorf("failed to delete command %s: %w", change.Name, err)
+		}
+	}
+
+	return diff, nil
+}
+
+// merge adds the changes of a guild diff.
+func (diff *CommandDiff) merge(guildDiff *CommandDiff, guildID discord.Snowflake) {
+	for _, change := range guildDiff.Added {
+		change.GuildID = guildID
+		diff.Added = append(diff.Added, change)
+	}
+
+	for _, change := range guildDiff.Changed {
+		change.GuildID = guildID
+		diff.Changed = append(diff.Changed, change)
+	}
+
+	for _, change := range guildDiff.Removed {
+		change.GuildID = guildID
+		diff.Removed = append(diff.Removed, change)
+	}
+
+	diff.Unchanged += guildDiff.Unchanged
+}
+
+// diffApplicationCommand returns the fields that differ between two commands.
+func diffApplicationCommand(current, desired discord.ApplicationCommand) (fields []string) {
+	if current.Description != desired.Description {
+		fields = append(fields, "description")
+	}
+
+	if !equalLocalizations(current.NameLocalizations, desired.NameLocalizations) {
+		fields = append(fields, "name_localizations")
+	}
+
+	if !equalLocalizations(current.DescriptionLocalizations, desired.DescriptionLocalizations) {
+		fields = append(fields, "description_localizations")
+	}
+
+	if !equalPermissions(current.DefaultMemberPermission, desired.DefaultMemberPermission) {
+		fields = append(fields, "default_member_permissions")
+	}
+
+	// Commands are usable in DMs unless specified.
+	if derefOr(current.DMPermission, true) != derefOr(desired.DMPermission, true) {
+		fields = append(fields, "dm_permission")
+	}
+
+	return append(fields, diffApplicationCommandOptions("options", current.Options, desired.Options)...)
+}
+
+// diffApplicationCommandOptions returns the fields that differ between two lists of options.
+// Options are matched by name. The order of subcommands is not significant.
+func diffApplicationCommandOptions(path string, current, desired []discord.ApplicationCommandOption) (fields []string) {
+	currentOptions := make(map[string]discord.ApplicationCommandOption, len(current))
+
+	for _, option := range current {
+		currentOptions[option.Name] = option
+	}
+
+	desiredOptions := make(map[string]bool, len(desired))
+
+	for _, option := range desired {
+		desiredOptions[option.Name] = true
+		optionPath := path + "." + option.Name
+
+		currentOption, ok := currentOptions[option.Name]
+		if !ok {
+			fields = append(fields, optionPath+" (added)")
+
+			continue
+		}
+
+		fields = append(fields, diffApplicationCommandOption(optionPath, currentOption, option)...)
+	}
+
+	for _, option := range current {
+		if !desiredOptions[option.Name] {
+			fields = append(fields, path+"."+option.Name+" (removed)")
+		}
+	}
+
+	if len(fields) == 0 && !slices.Equal(getArgumentOptionNames(current), getArgumentOptionNames(desired)) {
+		fields = append(fields, path+" (order)")
+	}
+
+	return fields
+}
+
+func diffApplicationCommandOption(path string, current, desired discord.ApplicationCommandOption) (fields []string) {
+	if current.Type != desired.Type {
+		fields = append(fields, path+".type")
+	}
+
+	if current.Description != desired.Description {
+		fields = append(fields, path+".description")
+	}
+
+	if current.Required != desired.Required {
+		fields = append(fields, path+".required")
+	}
+
+	if !equalLocalizations(current.NameLocalizations, desired.NameLocalizations) {
+		fields = append(fields, path+".name_localizations")
+	}
+
+	if !equalLocalizations(current.DescriptionLocalizations, desired.DescriptionLocalizations) {
+		fields = append(fields, path+".description_localizations")
+	}
+
+	if derefOr(current.Autocomplete, false) != derefOr(desired.Autocomplete, false) {
+		fields = append(fields, path+".autocomplete")
+	}
+
+	if !equalOptional(current.MinValue, desired.MinValue) || !equalOptional(current.MaxValue, desired.MaxValue) {
+		fields = append(fields, path+".value_bounds")
+	}
+
+	if !equalOptional(current.MinLength, desired.MinLength) || !equalOptional(current.MaxLength, desired.MaxLength) {
+		fields = append(fields, path+".length_bounds")
+	}
+
+	if !slices.Equal(current.ChannelTypes, desired.ChannelTypes) {
+		fields = append(fields, path+".channel_types")
+	}
+
+	if !equalChoices(current.Choices, desired.Choices) {
+		fields = append(fields, path+".choices")
+	}
+
+	return append(fields, diffApplicationCommandOptions(path, current.Options, desired.Options)...)
+}
+
+// getArgumentOptionNames returns the names of options that are not subcommands, as their order is significant.
+func getArgumentOptionNames(options []discord.ApplicationCommandOption) []string {
+	names := make([]string, 0, len(options))
+
+	for _, option := range options {
+		if option.Type != discord.ApplicationCommandOptionTypeSubCommand &&
+			option.Type != discord.ApplicationCommandOptionTypeSubCommandGroup {
+			names = append(names, option.Name)
+		}
+	}
+
+	return names
+}
+
+func getApplicationCommandKey(command discord.ApplicationCommand) string {
+	return fmt.Sprintf("%d:%s", getApplicationCommandType(command), command.Name)
+}
+
+func getApplicationCommandType(command discord.ApplicationCommand) discord.ApplicationCommandType {
+	return derefOr(command.Type, discord.ApplicationCommandTypeChatInput)
+}
+
+func getApplicationCommandID(command discord.ApplicationCommand) discord.Snowflake {
+	return derefOr(command.ID, 0)
+}
+
+func equalLocalizations(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for locale, value := range a {
+		if b[locale] != value {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalPermissions(a, b *discord.Int64) bool {
+	// Discord treats no permissions and permissions of 0 differently, so these are not defaulted.
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalOptional[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
+
+func equalChoices(a, b []discord.ApplicationCommandOptionChoice) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i].Name != b[i].Name || !equalLocalizations(a[i].NameLocalizations, b[i].NameLocalizations) {
+			return false
+		}
+
+		if !equalJSON(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalJSON compares two JSON values, ignoring whitespace.
+func equalJSON(a, b json.RawMessage) bool {
+	var compactA, compactB bytes.Buffer
+
+	if json.Compact(&compactA, a) != nil || json.Compact(&compactB, b) != nil {
+		return bytes.Equal(a, b)
+	}
+
+	return bytes.Equal(compactA.Bytes(), compactB.Bytes())
+}
+
+func derefOr[T any](value *T, fallback T) T {
+	if value == nil {
+		return fallback
+	}
+
+	return *value
+}
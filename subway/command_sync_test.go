@@ -0,0 +1,164 @@
+package internal
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+func newTestApplicationCommand(name string, options ...discord.ApplicationCommandOption) discord.ApplicationCommand {
+	id := discord.Snowflake(1)
+
+	return discord.ApplicationCommand{
+		ID:          &id,
+		Name:        name,
+		Description: name,
+		Options:     options,
+	}
+}
+
+func newTestApplicationCommandOption(name string, optionType discord.ApplicationCommandOptionType) discord.ApplicationCommandOption {
+	return discord.ApplicationCommandOption{
+		Type:        optionType,
+		Name:        name,
+		Description: name,
+	}
+}
+
+func TestDiffApplicationCommands(t *testing.T) {
+	user := newTestApplicationCommandOption("user", discord.ApplicationCommandOptionTypeUser)
+	reason := newTestApplicationCommandOption("reason", discord.ApplicationCommandOptionTypeString)
+
+	requiredUser := user
+	requiredUser.Required = true
+
+	localizedUser := user
+	localizedUser.DescriptionLocalizations = map[string]string{"fr": "utilisateur"}
+
+	localizedBan := newTestApplicationCommand("ban", user)
+	localizedBan.NameLocalizations = map[string]string{"fr": "bannir", "de": "bannen"}
+
+	reorderedLocalizedBan := newTestApplicationCommand("ban", user)
+	reorderedLocalizedBan.NameLocalizations = map[string]string{"de": "bannen", "fr": "bannir"}
+
+	group := newTestApplicationCommandOption("group", discord.ApplicationCommandOptionTypeSubCommandGroup)
+	group.Options = []discord.ApplicationCommandOption{user}
+
+	requiredGroup := group
+	requiredGroup.Options = []discord.ApplicationCommandOption{requiredUser}
+
+	tests := []struct {
+		name      string
+		current   []discord.ApplicationCommand
+		desired   []discord.ApplicationCommand
+		added     []string
+		removed   []string
+		changed   map[string][]string
+		unchanged int
+	}{
+		{
+			name:      "unchanged",
+			current:   []discord.ApplicationCommand{newTestApplicationCommand("ban", user, reason)},
+			desired:   []discord.ApplicationCommand{newTestApplicationCommand("ban", user, reason)},
+			unchanged: 1,
+		},
+		{
+			name:    "added and removed commands",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("kick")},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban")},
+			added:   []string{"ban"},
+			removed: []string{"kick"},
+		},
+		{
+			name:    "added option",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", user, reason)},
+			changed: map[string][]string{"ban": {"options.reason (added)"}},
+		},
+		{
+			name:    "removed option",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user, reason)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", user)},
+			changed: map[string][]string{"ban": {"options.reason (removed)"}},
+		},
+		{
+			name:    "changed option",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", requiredUser)},
+			changed: map[string][]string{"ban": {"options.user.required"}},
+		},
+		{
+			name:    "changed subcommand option",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", group)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", requiredGroup)},
+			changed: map[string][]string{"ban": {"options.group.user.required"}},
+		},
+		{
+			name:    "reordered options",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user, reason)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", reason, user)},
+			changed: map[string][]string{"ban": {"options (order)"}},
+		},
+		{
+			name:    "changed command localizations",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user)},
+			desired: []discord.ApplicationCommand{localizedBan},
+			changed: map[string][]string{"ban": {"name_localizations"}},
+		},
+		{
+			name:    "changed option localizations",
+			current: []discord.ApplicationCommand{newTestApplicationCommand("ban", user)},
+			desired: []discord.ApplicationCommand{newTestApplicationCommand("ban", localizedUser)},
+			changed: map[string][]string{"ban": {"options.user.description_localizations"}},
+		},
+		{
+			name:      "same localizations",
+			current:   []discord.ApplicationCommand{localizedBan},
+			desired:   []discord.ApplicationCommand{reorderedLocalizedBan},
+			unchanged: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			diff := DiffApplicationCommands(tt.current, tt.desired)
+
+			if got := getCommandChangeNames(diff.Added); !slices.Equal(got, tt.added) {
+				t.Errorf("Added = %v, want %v", got, tt.added)
+			}
+
+			if got := getCommandChangeNames(diff.Removed); !slices.Equal(got, tt.removed) {
+				t.Errorf("Removed = %v, want %v", got, tt.removed)
+			}
+
+			if len(diff.Changed) != len(tt.changed) {
+				t.Errorf("Changed = %v, want %v", diff.Changed, tt.changed)
+			}
+
+			for _, change := range diff.Changed {
+				if want := tt.changed[change.Name]; !slices.Equal(change.Fields, want) {
+					t.Errorf("Changed[%s].Fields = %v, want %v", change.Name, change.Fields, want)
+				}
+			}
+
+			if diff.Unchanged != tt.unchanged {
+				t.Errorf("Unchanged = %d, want %d", diff.Unchanged, tt.unchanged)
+			}
+
+			if want := len(tt.added) > 0 || len(tt.removed) > 0 || len(tt.changed) > 0; diff.HasChanges() != want {
+				t.Errorf("HasChanges() = %t, want %t", diff.HasChanges(), want)
+			}
+		})
+	}
+}
+
+func getCommandChangeNames(changes []CommandChange) []string {
+	var names []string
+
+	for _, change := range changes {
+		names = append(names, change.Name)
+	}
+
+	return names
+}
@@ -0,0 +1,311 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// Limits of application commands enforced by discord.
+const (
+	maxCommandDepth             = 3
+	maxCommandOptions           = 25
+	maxCommandChoices           = 25
+	maxCommandDescriptionLength = 100
+	maxCommandChoiceNameLength  = 100
+	maxCommandNameLength        = 32
+	maxCommandStringLength      = 6000
+)
+
+var commandNameRegex = regexp.MustCompile(`^[-_\p{L}\p{N}\p{Devanagari}\p{Thai}]{1,32}$`)
+
+// Validate checks the command and all of its subcommands and arguments against
+// the limits of discord. If called on a root, each command in it is validated.
+func (ic *InteractionCommandable) Validate() error {
+	if ic.parent == nil && ic.Name == "" {
+		for _, command := range ic.getSortedCommands() {
+			err := validateInteractionCommandable(command.Name, command, 1)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	// Commands that have not been added yet are validated as a top level command.
+	return validateInteractionCommandable(ic.getCommandPath(), ic, max(ic.getDepth(), 1))
+}
+
+// validateChild checks a command can be added as a child of this command.
+func (ic *InteractionCommandable) validateChild(child *InteractionCommandable) error {
+	path := ic.getCommandPath()
+
+	if ic.parent != nil {
+		if len(ic.commands) >= maxCommandOptions {
+			return newCommandValidationError(path, "options", fmt.Sprintf("cannot have more than %d subcommands", maxCommandOptions))
+		}
+
+		if len(ic.ArgumentParameter) > 0 {
+			return newCommandValidationError(path, "options", "cannot have both subcommands and arguments")
+		}
+	}
+
+	return validateInteractionCommandable(strings.TrimSpace(path+" "+child.Name), child, ic.getDepth()+1)
+}
+
+func validateInteractionCommandable(path string, ic *InteractionCommandable, depth int) error {
+	if depth > maxCommandDepth {
+		return newCommandValidationError(path, "name", "subcommand groups cannot be nested")
+	}
+
+	isChatInput := ic.CommandType == nil || *ic.CommandType == discord.ApplicationCommandTypeChatInput
+
+	if !isChatInput {
+		if depth > 1 {
+			return newCommandValidationError(path, "type", "context menu commands cannot be subcommands")
+		}
+
+		if utf8.RuneCountInString(ic.Name) < 1 || utf8.RuneCountInString(ic.Name) > maxCommandNameLength {
+			return newCommandValidationError(path, "name", fmt.Sprintf("must be between 1 and %d characters", maxCommandNameLength))
+		}
+
+		if ic.Description != "" {
+			return newCommandValidationError(path, "description", "context menu commands cannot have a description")
+		}
+
+		if len(ic.commands) > 0 || len(ic.ArgumentParameter) > 0 {
+			return newCommandValidationError(path, "options", "context menu commands cannot have options")
+		}
+
+		return nil
+	}
+
+	err := validateNameAndDescription(path, "", ic.Name, ic.Description, ic.NameLocalizations, ic.DescriptionLocalizations)
+	if err != nil {
+		return err
+	}
+
+	if len(ic.commands) > 0 && len(ic.ArgumentParameter) > 0 {
+		return newCommandValidationError(path, "options", "cannot have both subcommands and arguments")
+	}
+
+	if len(ic.commands) > maxCommandOptions {
+		return newCommandValidationError(path, "options", fmt.Sprintf("cannot have more than %d subcommands", maxCommandOptions))
+	}
+
+	for _, command := range ic.getSortedCommands() {
+		err = validateInteractionCommandable(path+" "+command.Name, command, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+
+	return validateArgumentParameters(path, ic.ArgumentParameter)
+}
+
+func validateArgumentParameters(path string, argumentParameters []ArgumentParameter) error {
+	if len(argumentParameters) > maxCommandOptions {
+		return newCommandValidationError(path, "options", fmt.Sprintf("cannot have more than %d arguments", maxCommandOptions))
+	}
+
+	names := make(map[string]bool, len(argumentParameters))
+	hasOptional := false
+
+	for _, argumentParameter := range argumentParameters {
+		field := "options." + argumentParameter.Name
+
+		err := validateNameAndDescription(path, field+".", argumentParameter.Name, argumentParameter.Description,
+			argumentParameter.NameLocalizations, argumentParameter.DescriptionLocalizations)
+		if err != nil {
+			return err
+		}
+
+		if names[argumentParameter.Name] {
+			return newCommandValidationError(path, field, "argument name is used more than once")
+		}
+
+		names[argumentParameter.Name] = true
+
+		if argumentParameter.Required && hasOptional {
+			return newCommandValidationError(path, field, "required arguments must be before optional arguments")
+		}
+
+		hasOptional = hasOptional || !argumentParameter.Required
+
+		err = validateArgumentChoices(path, field, argumentParameter)
+		if err != nil {
+			return err
+		}
+
+		err = validateArgumentBounds(path, field, argumentParameter)
+		if err != nil {
+			return err
+		}
+
+		err = validateArgumentDefault(path, field, argumentParameter)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateArgumentChoices(path, field string, argumentParameter ArgumentParameter) error {
+	if len(argumentParameter.Choices) > maxCommandChoices {
+		return newCommandValidationError(path, field+".choices", fmt.Sprintf("cannot have more than %d choices", maxCommandChoices))
+	}
+
+	if len(argumentParameter.Choices) > 0 && argumentParameter.Autocomplete != nil && *argumentParameter.Autocomplete {
+		return newCommandValidationError(path, field+".autocomplete", "cannot be used with choices")
+	}
+
+	for _, choice := range argumentParameter.Choices {
+		if utf8.RuneCountInString(choice.Name) < 1 || utf8.RuneCountInString(choice.Name) > maxCommandChoiceNameLength {
+			return newCommandValidationError(path, field+".choices",
+				fmt.Sprintf("choice %q must be between 1 and %d characters", choice.Name, maxCommandChoiceNameLength))
+		}
+	}
+
+	return nil
+}
+
+func validateArgumentBounds(path, field string, argumentParameter ArgumentParameter) error {
+	if minValue, maxValue := getArgumentBounds(argumentParameter); minValue != nil && maxValue != nil && *minValue > *maxValue {
+		return newCommandValidationError(path, field+".min_value", "cannot be greater than max_value")
+	}
+
+	if argumentParameter.MinLength != nil && (*argumentParameter.MinLength < 0 || *argumentParameter.MinLength > maxCommandStringLength) {
+		return newCommandValidationError(path, field+".min_length", fmt.Sprintf("must be between 0 and %d", maxCommandStringLength))
+	}
+
+	if argumentParameter.MaxLength != nil && (*argumentParameter.MaxLength < 1 || *argumentParameter.MaxLength > maxCommandStringLength) {
+		return newCommandValidationError(path, field+".max_length", fmt.Sprintf("must be between 1 and %d", maxCommandStringLength))
+	}
+
+	if argumentParameter.MinLength != nil && argumentParameter.MaxLength != nil &&
+		*argumentParameter.MinLength > *argumentParameter.MaxLength {
+		return newCommandValidationError(path, field+".min_length", "cannot be greater than max_length")
+	}
+
+	return nil
+}
+
+func validateArgumentDefault(path, field string, argumentParameter ArgumentParameter) error {
+	if argumentParameter.Default == nil {
+		return nil
+	}
+
+	if argumentParameter.Required {
+		return newCommandValidationError(path, field+".default", "required arguments cannot have a default")
+	}
+
+	// Accessors such as Int() expect the exact type the converter outputs.
+	if goType, ok := argumentGoTypes[argumentParameter.ArgumentType]; ok && !reflect.TypeOf(argumentParameter.Default).AssignableTo(goType) {
+		return newCommandValidationError(path, field+".default", fmt.Sprintf("must be a %s", goType))
+	}
+
+	return nil
+}
+
+// validateNameAndDescription checks the name and description of a chat input command or argument.
+func validateNameAndDescription(path, fieldPrefix, name, description string, nameLocalizations, descriptionLocalizations map[string]string) error {
+	err := validateCommandName(path, fieldPrefix+"name", name)
+	if err != nil {
+		return err
+	}
+
+	err = validateCommandDescription(path, fieldPrefix+"description", description)
+	if err != nil {
+		return err
+	}
+
+	for locale, localizedName := range nameLocalizations {
+		err = validateCommandName(path, fieldPrefix+"name_localizations."+locale, localizedName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for locale, localizedDescription := range descriptionLocalizations {
+		err = validateCommandDescription(path, fieldPrefix+"description_localizations."+locale, localizedDescription)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateCommandName(path, field, name string) error {
+	if !commandNameRegex.MatchString(name) {
+		return newCommandValidationError(path, field,
+			fmt.Sprintf("%q must be between 1 and %d letters, numbers, dashes or underscores", name, maxCommandNameLength))
+	}
+
+	if strings.ToLower(name) != name {
+		return newCommandValidationError(path, field, fmt.Sprintf("%q must be lowercase", name))
+	}
+
+	return nil
+}
+
+func validateCommandDescription(path, field, description string) error {
+	if length := utf8.RuneCountInString(description); length < 1 || length > maxCommandDescriptionLength {
+		return newCommandValidationError(path, field,
+			fmt.Sprintf("must be between 1 and %d characters, got %d", maxCommandDescriptionLength, length))
+	}
+
+	return nil
+}
+
+func newCommandValidationError(path, field, reason string) *CommandValidationError {
+	return &CommandValidationError{
+		Command: path,
+		Field:   field,
+		Reason:  reason,
+	}
+}
+
+// getCommandPath returns the full name of the command, such as "settings roles add".
+func (ic *InteractionCommandable) getCommandPath() string {
+	names := make([]string, 0)
+
+	for command := ic; command != nil; command = command.parent {
+		if command.Name != "" {
+			names = append(names, command.Name)
+		}
+	}
+
+	slices.Reverse(names)
+
+	return strings.Join(names, " ")
+}
+
+// getDepth returns how deeply nested the command is. Top level commands have a depth of 1.
+func (ic *InteractionCommandable) getDepth() int {
+	depth := 0
+
+	for command := ic; command.parent != nil; command = command.parent {
+		depth++
+	}
+
+	return depth
+}
+
+// getSortedCommands returns the commands ordered by name.
+func (ic *InteractionCommandable) getSortedCommands() []*InteractionCommandable {
+	commands := ic.GetAllCommands()
+
+	slices.SortFunc(commands, func(a, b *InteractionCommandable) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return commands
+}
@@ -26,4 +26,12 @@ const (
 	ArgumentTypeInt
 	ArgumentTypeFloat
 	ArgumentTypeStrings
+	ArgumentTypeAttachment
+	ArgumentTypeMentionable
+	ArgumentTypeDuration
+	ArgumentTypeTimestamp
+	ArgumentTypeTimezone
+	ArgumentTypeMessage
+	ArgumentTypeInvite
+	ArgumentTypeWebhook
 )
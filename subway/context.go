@@ -5,6 +5,7 @@ import (
 	"net/url"
 
 	"github.com/WelcomerTeam/Discord/discord"
+	"github.com/rs/zerolog"
 )
 
 type SubwayContextKey int
@@ -20,6 +21,12 @@ const (
 	IdentifierKey
 	ComponentListenerKey
 	URLKey
+	ModalListenerKey
+	RawInteractionKey
+	InteractionAcknowledgementKey
+	ApplicationKey
+	LoggerKey
+	ArgumentStructKey
 )
 
 // URL context handler.
@@ -133,3 +140,87 @@ func GetComponentListenerFromContext(ctx context.Context) *ComponentListener {
 
 	return value
 }
+
+// ModalListener context handler.
+func AddModalListenerToContext(ctx context.Context, v *ModalListener) context.Context {
+	return context.WithValue(ctx, ModalListenerKey, v)
+}
+
+func GetModalListenerFromContext(ctx context.Context) *ModalListener {
+	value, ok := ctx.Value(ModalListenerKey).(*ModalListener)
+	if !ok {
+		panic("GetModalListenerFromContext(): failed to get ModalListener from context")
+	}
+
+	return value
+}
+
+// RawInteraction context handler.
+func AddRawInteractionToContext(ctx context.Context, v []byte) context.Context {
+	return context.WithValue(ctx, RawInteractionKey, v)
+}
+
+func GetRawInteractionFromContext(ctx context.Context) []byte {
+	value, ok := ctx.Value(RawInteractionKey).([]byte)
+	if !ok {
+		panic("GetRawInteractionFromContext(): failed to get RawInteraction from context")
+	}
+
+	return value
+}
+
+// InteractionAcknowledgement context handler.
+func AddInteractionAcknowledgementToContext(ctx context.Context, v *InteractionAcknowledgement) context.Context {
+	return context.WithValue(ctx, InteractionAcknowledgementKey, v)
+}
+
+func GetInteractionAcknowledgementFromContext(ctx context.Context) *InteractionAcknowledgement {
+	value, ok := ctx.Value(InteractionAcknowledgementKey).(*InteractionAcknowledgement)
+	if !ok {
+		panic("GetInteractionAcknowledgementFromContext(): failed to get InteractionAcknowledgement from context")
+	}
+
+	return value
+}
+
+// Application context handler.
+func AddApplicationToContext(ctx context.Context, v *Application) context.Context {
+	return context.WithValue(ctx, ApplicationKey, v)
+}
+
+func GetApplicationFromContext(ctx context.Context) *Application {
+	value, ok := ctx.Value(ApplicationKey).(*Application)
+	if !ok {
+		panic("GetApplicationFromContext(): failed to get Application from context")
+	}
+
+	return value
+}
+
+// Logger context handler.
+func AddLoggerToContext(ctx context.Context, v *zerolog.Logger) context.Context {
+	return context.WithValue(ctx, LoggerKey, v)
+}
+
+func GetLoggerFromContext(ctx context.Context) *zerolog.Logger {
+	value, ok := ctx.Value(LoggerKey).(*zerolog.Logger)
+	if !ok {
+		panic("GetLoggerFromContext(): failed to get Logger from context")
+	}
+
+	return value
+}
+
+// ArgumentStruct context handler.
+func AddArgumentStructToContext(ctx context.Context, v interface{}) context.Context {
+	return context.WithValue(ctx, ArgumentStructKey, v)
+}
+
+func GetArgumentStructFromContext(ctx context.Context) interface{} {
+	value := ctx.Value(ArgumentStructKey)
+	if value == nil {
+		panic("GetArgumentStructFromContext(): failed to get ArgumentStruct from context")
+	}
+
+	return value
+}
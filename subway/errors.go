@@ -7,9 +7,15 @@ import (
 
 var (
 	ErrSubwayAlreadyExists = errors.New("subway already created")
+	ErrSubwayShuttingDown  = errors.New("subway is shutting down")
 
-	ErrInvalidRequestSignature = errors.New("invalid request signature")
-	ErrInvalidPublicKey        = errors.New("invalid public key. this must be a 64 characters long and hex encoded")
+	ErrInteractionQueueTimeout = errors.New("interaction exceeded the queue timeout")
+
+	ErrInvalidRequestSignature   = errors.New("invalid request signature")
+	ErrInvalidSignatureTimestamp = errors.New("invalid request signature timestamp")
+	ErrStaleSignatureTimestamp   = errors.New("request signature timestamp is too old")
+	ErrFutureSignatureTimestamp  = errors.New("request signature timestamp is in the future")
+	ErrInvalidPublicKey          = errors.New("invalid public key. this must be a 64 characters long and hex encoded")
 
 	ErrReadConfigurationFailure = errors.New("failed to read configuration")
 	ErrLoadConfigurationFailure = errors.New("failed to load configuration")
@@ -17,42 +23,99 @@ var (
 	ErrFetchMissingGuild     = errors.New("object requires guild ID to fetch")
 	ErrFetchMissingSnowflake = errors.New("object requires snowflake to fetch")
 
-	ErrCogAlreadyRegistered     = errors.New("cog with this name already exists")
-	ErrCommandAlreadyRegistered = errors.New("command with this name already exists")
-	ErrInvalidArgumentType      = errors.New("argument value is not correct type for converter used")
-	ErrConversionError          = errors.New("failed to convert argument to desired type")
+	ErrCogAlreadyRegistered         = errors.New("cog with this name already exists")
+	ErrApplicationAlreadyRegistered = errors.New("application with this id already exists")
+	ErrMissingApplicationID         = errors.New("application requires an id")
+	ErrMissingApplicationToken      = errors.New("application requires a token to sync commands")
+	ErrCommandAlreadyRegistered     = errors.New("command with this name already exists")
+	ErrInvalidCommand               = errors.New("command is not valid")
+	ErrInvalidArgumentStruct        = errors.New("argument struct is not valid")
+	ErrInvalidArgumentType          = errors.New("argument value is not correct type for converter used")
+	ErrArgumentOverflow             = errors.New("argument value does not fit in the field")
+	ErrConversionError              = errors.New("failed to convert argument to desired type")
 
 	ErrCommandNotFound             = errors.New("command with this name was not found")
 	ErrCommandAutoCompleteNotFound = errors.New("autocomplete for command with this name was not found")
 	ErrComponentListenerNotFound   = errors.New("component listener with this name was not found or has expired")
+	ErrModalListenerNotFound       = errors.New("modal listener with this name was not found or has expired")
+	ErrModalTimeout                = errors.New("modal was not submitted before the timeout")
 
 	ErrCheckFailure            = errors.New("command failed built-in checks")
 	ErrMissingRequiredArgument = errors.New("command missing required arguments")
 	ErrArgumentNotFound        = errors.New("command argument was not found")
 	ErrConverterNotFound       = errors.New("command converter is not setup")
+	ErrInvalidArgument         = errors.New("command argument does not meet its constraints")
 
 	// Converter errors.
 
-	ErrSnowflakeNotFound = errors.New("id does not follow a valid id or mention format")
-	ErrMemberNotFound    = errors.New("member provided was not found")
-	ErrUserNotFound      = errors.New("user provided was not found")
-	ErrChannelNotFound   = errors.New("channel provided was not found")
-	ErrGuildNotFound     = errors.New("guild provided was not found")
-	ErrRoleNotFound      = errors.New("role provided was not found")
-	ErrEmojiNotFound     = errors.New("emoji provided was not found")
+	ErrSnowflakeNotFound   = errors.New("id does not follow a valid id or mention format")
+	ErrMemberNotFound      = errors.New("member provided was not found")
+	ErrUserNotFound        = errors.New("user provided was not found")
+	ErrChannelNotFound     = errors.New("channel provided was not found")
+	ErrGuildNotFound       = errors.New("guild provided was not found")
+	ErrRoleNotFound        = errors.New("role provided was not found")
+	ErrEmojiNotFound       = errors.New("emoji provided was not found")
+	ErrAttachmentNotFound  = errors.New("attachment provided was not found")
+	ErrMentionableNotFound = errors.New("user or role provided was not found")
+	ErrMessageNotFound     = errors.New("message provided was not found")
 
 	ErrBadInviteArgument  = errors.New("invite provided was invalid or expired")
+	ErrBadMessageArgument = errors.New("message link provided was not in valid format")
 	ErrBadColourArgument  = errors.New("colour provided was not in valid format")
 	ErrBadBoolArgument    = errors.New("bool provided was not in valid format")
 	ErrBadIntArgument     = errors.New("int provided was not in valid format")
 	ErrBadFloatArgument   = errors.New("float provided was not in valid format")
 	ErrBadWebhookArgument = errors.New("webhook url provided was not in valid format")
+
+	ErrBadDurationArgument  = errors.New("duration provided was not in valid format")
+	ErrBadTimestampArgument = errors.New("time provided was not in valid format")
+	ErrBadTimezoneArgument  = errors.New("timezone provided was not a valid timezone")
 )
 
 type PanicError struct {
 	Recover interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
 }
 
 func (cp PanicError) Error() string {
 	return fmt.Sprintf("command panicked with error: %v", cp.Recover)
 }
+
+// CommandValidationError is returned when a command does not follow the limits of discord.
+type CommandValidationError struct {
+	// Command is the full name of the command, such as "settings roles add".
+	Command string
+
+	// Field is the field that is not valid, such as "options.role.description".
+	Field string
+
+	Reason string
+}
+
+func (cv *CommandValidationError) Error() string {
+	return fmt.Sprintf("invalid command %q: %s %s", cv.Command, cv.Field, cv.Reason)
+}
+
+func (cv *CommandValidationError) Unwrap() error {
+	return ErrInvalidCommand
+}
+
+// ArgumentValidationError is returned when an argument does not meet the constraints of its parameter.
+type ArgumentValidationError struct {
+	Argument string
+
+	// Constraint is the constraint that was not met, such as ArgumentConstraintMaxLength.
+	Constraint string
+
+	Reason string
+}
+
+func (av *ArgumentValidationError) Error() string {
+	return fmt.Sprintf("invalid argument %q: %s %s", av.Argument, av.Constraint, av.Reason)
+}
+
+func (av *ArgumentValidationError) Unwrap() error {
+	return ErrInvalidArgument
+}
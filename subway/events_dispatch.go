@@ -10,8 +10,10 @@ import (
 
 // ProcessApplicationCommandInteraction processes the application command that has been received.
 func (sub *Subway) ProcessApplicationCommandInteraction(ctx context.Context, interaction discord.Interaction) (*discord.InteractionResponse, error) {
+	commands := sub.getCommands(ctx)
+
 	commandTree := constructCommandTree(interaction.Data.Options, make([]string, 0))
-	command := sub.Commands.GetCommand(interaction.Data.Name)
+	command := commands.GetCommand(interaction.Data.Name)
 
 	// Create interaction context
 	ctx = AddInteractionCommandToContext(ctx, command)
@@ -21,13 +23,13 @@ func (sub *Subway) ProcessApplicationCommandInteraction(ctx context.Context, int
 	ctx = AddCommandTreeToContext(ctx, commandTree)
 
 	if command == nil {
-		return sub.Commands.propagateError(ctx, sub, interaction, ErrCommandNotFound), ErrCommandNotFound
+		return commands.propagateError(ctx, sub, interaction, ErrCommandNotFound), ErrCommandNotFound
 	}
 
 	if sub.OnBeforeInteraction != nil {
 		err := sub.OnBeforeInteraction(ctx, sub, interaction)
 		if err != nil {
-			return sub.Commands.propagateError(ctx, sub, interaction, err), err
+			return commands.propagateError(ctx, sub, interaction, err), err
 		}
 	}
 
@@ -36,7 +38,7 @@ func (sub *Subway) ProcessApplicationCommandInteraction(ctx context.Context, int
 	if sub.OnAfterInteraction != nil {
 		onAfterInteractionErr := sub.OnAfterInteraction(ctx, sub, interaction, response, err)
 		if onAfterInteractionErr != nil {
-			return sub.Commands.propagateError(ctx, sub, interaction, onAfterInteractionErr), onAfterInteractionErr
+			return commands.propagateError(ctx, sub, interaction, onAfterInteractionErr), onAfterInteractionErr
 		}
 	}
 
@@ -74,6 +76,96 @@ func (sub *Subway) ProcessMessageComponentInteraction(ctx context.Context, inter
 	return listener.Handler(ctx, sub, interaction)
 }
 
+// ProcessModalSubmitInteraction processes the modal submission that has been received.
+// Text inputs are available as string arguments named after their custom ID.
+func (sub *Subway) ProcessModalSubmitInteraction(ctx context.Context, interaction discord.Interaction) (*discord.InteractionResponse, error) {
+	sub.ModalListenersMu.RLock()
+
+	// Listeners of the user that submitted the modal are used over listeners for any user.
+	listener, hasListener := sub.ModalListeners[getUserModalListenerKey(interaction.Data.CustomID, getInteractionUserID(interaction))]
+	if !hasListener {
+		listener, hasListener = sub.ModalListeners[interaction.Data.CustomID]
+	}
+
+	sub.ModalListenersMu.RUnlock()
+
+	if !hasListener {
+		return nil, ErrModalListenerNotFound
+	}
+
+	arguments := make(map[string]*Argument)
+
+	// The text input values are not part of discord.InteractionComponent, so these
+	// are read from the raw payload, if it has been provided.
+	rawInteraction, _ := ctx.Value(RawInteractionKey).([]byte)
+
+	var err error
+
+	arguments, err = parseModalData(arguments, rawInteraction)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = AddModalListenerToContext(ctx, listener)
+	ctx = AddArgumentsToContext(ctx, arguments)
+
+	if listener.Handler == nil {
+		if !listener.deliver(&ModalSubmission{Interaction: &interaction, Arguments: arguments}) {
+			return nil, ErrModalListenerNotFound
+		}
+
+		return nil, nil
+	}
+
+	return listener.Handler(ctx, sub, interaction)
+}
+
+type modalSubmitComponent struct {
+	CustomID   string                           `json:"custom_id"`
+	Value      string                           `json:"value"`
+	Components []modalSubmitComponent           `json:"components"`
+	Type       discord.InteractionComponentType `json:"type"`
+}
+
+type modalSubmitPayload struct {
+	Data struct {
+		Components []modalSubmitComponent `json:"components"`
+	} `json:"data"`
+}
+
+// parseModalData generates the arguments for a modal submission.
+func parseModalData(arguments map[string]*Argument, rawInteraction []byte) (map[string]*Argument, error) {
+	if len(rawInteraction) == 0 {
+		return arguments, nil
+	}
+
+	var payload modalSubmitPayload
+
+	err := json.Unmarshal(rawInteraction, &payload)
+	if err != nil {
+		return arguments, fmt.Errorf("failed to unmarshal modal data: %w", err)
+	}
+
+	return extractModalComponents(payload.Data.Components, arguments), nil
+}
+
+func extractModalComponents(components []modalSubmitComponent, arguments map[string]*Argument) map[string]*Argument {
+	for _, component := range components {
+		if component.Type == discord.InteractionComponentTypeTextInput {
+			arguments[component.CustomID] = &Argument{
+				ArgumentType: ArgumentTypeString,
+				value:        component.Value,
+			}
+		}
+
+		if len(component.Components) > 0 {
+			arguments = extractModalComponents(component.Components, arguments)
+		}
+	}
+
+	return arguments
+}
+
 // parseComponentData generates the arguments for a component interaction.
 func parseComponentData(arguments map[string]*Argument, data *discord.InteractionData) (map[string]*Argument, error) {
 	// Now, for simplicity, we will just return the string list we receive from discord.
@@ -129,7 +221,7 @@ func constructCommandTree(options []discord.InteractionDataOption, tree []string
 // CanRun checks all global bot checks and returns if the message passes them all.
 // If an error occurs, the message will be treated as not being able to run.
 func (sub *Subway) CanRun(ctx context.Context, interaction discord.Interaction) (bool, error) {
-	for _, check := range sub.Commands.Checks {
+	for _, check := range sub.getCommands(ctx).Checks {
 		canRun, err := check(ctx, sub, interaction)
 		if err != nil {
 			return false, err
@@ -152,9 +244,14 @@ func (sub *Subway) MustRegisterCog(cog Cog) {
 }
 
 func (sub *Subway) RegisterCog(cog Cog) error {
+	return sub.registerCog(sub.Cogs, sub.Commands, cog)
+}
+
+// registerCog registers a cog and adds its commands to the command tree passed.
+func (sub *Subway) registerCog(cogs map[string]Cog, commands *InteractionCommandable, cog Cog) error {
 	cogInfo := cog.CogInfo()
 
-	if _, ok := sub.Cogs[cogInfo.Name]; ok {
+	if _, ok := cogs[cogInfo.Name]; ok {
 		return ErrCogAlreadyRegistered
 	}
 
@@ -164,7 +261,14 @@ func (sub *Subway) RegisterCog(cog Cog) error {
 		return fmt.Errorf("failed to register cog: %w", err)
 	}
 
-	sub.Cogs[cogInfo.Name] = cog
+	if cast, ok := cog.(CogWithInteractionCommands); ok {
+		err := validateCogCommands(commands, cast.GetInteractionCommandable())
+		if err != nil {
+			return fmt.Errorf("failed to register cog %s: %w", cogInfo.Name, err)
+		}
+	}
+
+	cogs[cogInfo.Name] = cog
 
 	sub.Logger.Info().Str("cog", cogInfo.Name).Msg("Loaded Cog")
 
@@ -182,19 +286,44 @@ func (sub *Subway) RegisterCog(cog Cog) error {
 			Int("commands", len(interactionCommandable.GetAllCommands())).
 			Msg("Cog has interaction commands")
 
-		sub.RegisterCogInteractionCommandable(cog, interactionCommandable)
+		sub.registerInteractionCommandable(commands, interactionCommandable)
+	}
+
+	return nil
+}
+
+// validateCogCommands checks the commands of a cog are valid and are able to be added to the command tree.
+func validateCogCommands(commands, interactionCommandable *InteractionCommandable) error {
+	err := interactionCommandable.Validate()
+	if err != nil {
+		return err
+	}
+
+	for _, command := range interactionCommandable.GetAllCommands() {
+		if _, ok := commands.getCommand(command.Name); ok {
+			return fmt.Errorf("%s: %w", command.Name, ErrCommandAlreadyRegistered)
+		}
 	}
 
 	return nil
 }
 
 func (sub *Subway) RegisterCogInteractionCommandable(cog Cog, interactionCommandable *InteractionCommandable) {
+	sub.registerInteractionCommandable(sub.Commands, interactionCommandable)
+}
+
+func (sub *Subway) registerInteractionCommandable(commands, interactionCommandable *InteractionCommandable) {
 	for _, command := range interactionCommandable.GetAllCommands() {
 		// Add Cog checks to all commands.
 		command.Checks = append(interactionCommandable.Checks, command.Checks...)
 
+		// Commands inherit the scope of the cog.
+		if command.Scope == nil {
+			command.Scope = interactionCommandable.Scope
+		}
+
 		sub.Logger.Debug().Str("name", command.Name).Msg("Registering interaction command")
 
-		sub.Commands.MustAddInteractionCommand(command)
+		commands.MustAddInteractionCommand(command)
 	}
 }
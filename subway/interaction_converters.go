@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"image/color"
+	"reflect"
 	"regexp"
 	"strconv"
 	"sync"
+	"time"
 
 	discord "github.com/WelcomerTeam/Discord/discord"
 	sandwich "github.com/WelcomerTeam/Sandwich/sandwich"
@@ -39,11 +41,52 @@ type ArgumentParameter struct {
 	MinLength    *int32
 	MaxLength    *int32
 	Autocomplete *bool
+
+	// MinNumber and MaxNumber are fractional bounds of ArgumentTypeFloat arguments and are used
+	// instead of MinValue and MaxValue. The discord library only sends whole bounds, so they are
+	// rounded outwards when registering and enforced exactly when preparing the command. Discord
+	// shows the rounded bounds, so a MaxNumber of 2.5 allows 3 to be entered, which is then
+	// rejected with an ArgumentValidationError once the command has been submitted.
+	MinNumber *float64
+	MaxNumber *float64
+
+	// Default is the value of the argument when it is not provided. Defaults to
+	// the default value of the converter. Must be the type the converter outputs.
+	Default interface{}
+}
+
+// Mentionable is the value of a mentionable argument, which is either a user or a role.
+// Member is also set when a user was mentioned in a guild.
+type Mentionable struct {
+	User   *discord.User
+	Member *discord.GuildMember
+	Role   *discord.Role
+}
+
+// ID returns the ID of the mentioned user or role.
+func (m Mentionable) ID() discord.Snowflake {
+	switch {
+	case m.User != nil:
+		return m.User.ID
+	case m.Role != nil:
+		return m.Role.ID
+	default:
+		return 0
+	}
 }
 
 type Argument struct {
 	ArgumentType ArgumentType
 	value        interface{}
+
+	// provided is true if the value was provided by the user, rather than being a default.
+	provided bool
+}
+
+// Provided returns true if the argument was provided by the user. If not, the value of the
+// argument is its default.
+func (a *Argument) Provided() bool {
+	return a.provided
 }
 
 type InteractionArgumentConverterType func(ctx context.Context, sub *Subway, interaction discord.Interaction, argument discord.InteractionDataOption) (out interface{}, err error)
@@ -56,17 +99,78 @@ type InteractionConverters struct {
 type InteractionConverter struct {
 	converterType InteractionArgumentConverterType
 	data          interface{}
+	outputType    reflect.Type
+
+	optionType   discord.ApplicationCommandOptionType
+	channelTypes []discord.ChannelType
+	autocomplete InteractionAutocompleteHandler
+}
+
+// InteractionConverterOptions represents the options to register a converter.
+type InteractionConverterOptions struct {
+	Converter    InteractionArgumentConverterType
+	DefaultValue interface{}
+
+	// OutputType is the type the converter outputs, used to check argument struct fields
+	// when commands are added. Defaults to the type of DefaultValue.
+	OutputType reflect.Type
+
+	// OptionType is the type of option arguments are registered with discord as.
+	// Defaults to a string option.
+	OptionType discord.ApplicationCommandOptionType
+
+	// ChannelTypes are the channel types of channel options, used if the argument does not set any.
+	ChannelTypes []discord.ChannelType
+
+	// Autocomplete suggests values for arguments using the converter. Arguments without choices are
+	// registered with autocomplete, and it is used if the command does not have an AutocompleteHandler.
+	Autocomplete InteractionAutocompleteHandler
 }
 
 // RegisterConverter adds a new converter. If there is already a
-// converter registered with its name, it will be overridden.
+// converter registered with its name, it will be overridden and
+// keep the option type, channel types and autocomplete it had.
 func (co *InteractionConverters) RegisterConverter(converterName ArgumentType, converter InteractionArgumentConverterType, defaultValue interface{}) {
 	co.convertersMu.Lock()
 	defer co.convertersMu.Unlock()
 
-	co.Converters[converterName] = &InteractionConverter{
+	interactionConverter := &InteractionConverter{
 		converterType: converter,
 		data:          defaultValue,
+		outputType:    reflect.TypeOf(defaultValue),
+		optionType:    discord.ApplicationCommandOptionTypeString,
+	}
+
+	if existing, ok := co.Converters[converterName]; ok {
+		interactionConverter.optionType = existing.optionType
+		interactionConverter.channelTypes = existing.channelTypes
+		interactionConverter.autocomplete = existing.autocomplete
+	}
+
+	co.Converters[converterName] = interactionConverter
+}
+
+// RegisterConverterWithOptions adds a new converter with the option type it is registered with discord as.
+// If there is already a converter registered with its name, it will be overridden.
+func (co *InteractionConverters) RegisterConverterWithOptions(converterName ArgumentType, options InteractionConverterOptions) {
+	co.convertersMu.Lock()
+	defer co.convertersMu.Unlock()
+
+	if options.OptionType == 0 {
+		options.OptionType = discord.ApplicationCommandOptionTypeString
+	}
+
+	if options.OutputType == nil {
+		options.OutputType = reflect.TypeOf(options.DefaultValue)
+	}
+
+	co.Converters[converterName] = &InteractionConverter{
+		converterType: options.Converter,
+		data:          options.DefaultValue,
+		outputType:    options.OutputType,
+		optionType:    options.OptionType,
+		channelTypes:  options.ChannelTypes,
+		autocomplete:  options.Autocomplete,
 	}
 }
 
@@ -119,28 +223,36 @@ func HandleInteractionArgumentTypeMember(ctx context.Context, sub *Subway, inter
 		return nil, nil
 	}
 
-	var argument string
-
-	err = json.Unmarshal(option.Value, &argument)
+	snowflake, err := getOptionSnowflake(option)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+		return nil, err
 	}
 
-	snowflakeID, _ := strconv.ParseInt(argument, 10, 64)
+	resolved := getResolvedData(interaction)
 
-	snowflake := discord.Snowflake(snowflakeID)
+	result, ok := resolved.Members[snowflake]
+	if ok {
+		userResult, ok := resolved.Users[snowflake]
+		if ok {
+			result.User = &userResult
 
-	result, ok := interaction.Data.Resolved.Members[snowflake]
+			return result, nil
+		}
+
+		sub.getLogger(ctx).Warn().Int64("id", int64(snowflake)).Msg("Member present in interaction resolved, but no User is present")
+	}
 
-	if !ok {
+	if snowflake.IsNil() || interaction.GuildID == nil {
 		return nil, ErrMemberNotFound
 	}
 
-	userResult, ok := interaction.Data.Resolved.Users[snowflake]
-	if ok {
-		result.User = &userResult
-	} else {
-		sub.Logger.Warn().Int64("id", snowflakeID).Msg("Member present in interaction resolved, but no User is present")
+	result, err = sub.GRPCInterface.FetchMemberByID(sub.NewGRPCContext(ctx), *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch member: %w", err)
+	}
+
+	if result.User == nil || result.User.ID.IsNil() {
+		return nil, ErrMemberNotFound
 	}
 
 	return result, nil
@@ -154,22 +266,31 @@ func HandleInteractionArgumentTypeUser(ctx context.Context, sub *Subway, interac
 		return nil, nil
 	}
 
-	var argument string
-
-	err = json.Unmarshal(option.Value, &argument)
+	snowflake, err := getOptionSnowflake(option)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+		return nil, err
 	}
 
-	snowflakeID, _ := strconv.ParseInt(argument, 10, 64)
+	result, ok := getResolvedData(interaction).Users[snowflake]
+	if ok && !result.ID.IsNil() {
+		return result, nil
+	}
 
-	result := interaction.Data.Resolved.Users[discord.Snowflake(snowflakeID)]
+	// Users can only be fetched as a member of the guild the interaction is from.
+	if snowflake.IsNil() || interaction.GuildID == nil {
+		return nil, ErrUserNotFound
+	}
 
-	if result.ID.IsNil() {
+	member, err := sub.GRPCInterface.FetchMemberByID(sub.NewGRPCContext(ctx), *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch member: %w", err)
+	}
+
+	if member.User == nil || member.User.ID.IsNil() {
 		return nil, ErrUserNotFound
 	}
 
-	return result, nil
+	return *member.User, nil
 }
 
 // HandleInteractionArgumentTypeGuildChannel handles converting from a string
@@ -180,16 +301,24 @@ func HandleInteractionArgumentTypeGuildChannel(ctx context.Context, sub *Subway,
 		return nil, nil
 	}
 
-	var argument string
-
-	err = json.Unmarshal(option.Value, &argument)
+	snowflake, err := getOptionSnowflake(option)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+		return nil, err
 	}
 
-	snowflakeID, _ := strconv.ParseInt(argument, 10, 64)
+	result, ok := getResolvedData(interaction).Channels[snowflake]
+	if ok && !result.ID.IsNil() {
+		return result, nil
+	}
 
-	result := interaction.Data.Resolved.Channels[discord.Snowflake(snowflakeID)]
+	if snowflake.IsNil() || interaction.GuildID == nil {
+		return nil, ErrChannelNotFound
+	}
+
+	result, err = sub.GRPCInterface.FetchChannelByID(sub.NewGRPCContext(ctx), *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch channel: %w", err)
+	}
 
 	if result.ID.IsNil() {
 		return nil, ErrChannelNotFound
@@ -254,18 +383,27 @@ func HandleInteractionArgumentTypeRole(ctx context.Context, sub *Subway, interac
 		return nil, nil
 	}
 
-	var argument string
-
-	err = json.Unmarshal(option.Value, &argument)
+	snowflake, err := getOptionSnowflake(option)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+		return nil, err
 	}
 
-	snowflakeID, _ := strconv.ParseInt(argument, 10, 64)
+	result, ok := getResolvedData(interaction).Roles[snowflake]
+	if ok && !result.ID.IsNil() {
+		return result, nil
+	}
 
-	result := interaction.Data.Resolved.Roles[discord.Snowflake(snowflakeID)]
+	if snowflake.IsNil() || interaction.GuildID == nil {
+		return nil, ErrRoleNotFound
+	}
 
-	if result.ID.IsNil() {
+	result, err = sub.GRPCInterface.FetchRoleByID(sub.NewGRPCContext(ctx), *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch role: %w", err)
+	}
+
+	// Sandwich may return a different role, such as @everyone, if the role was not found.
+	if result.ID != snowflake {
 		return nil, ErrRoleNotFound
 	}
 
@@ -466,22 +604,34 @@ func HandleInteractionArgumentTypeInt(ctx context.Context, sub *Subway, interact
 	return argument, nil
 }
 
-// HandleInteractionArgumentTypeFloat handles converting from a string
+// HandleInteractionArgumentTypeFloat handles converting from a number
 // argument into a Float type. Use .Float64() within a command
 // to get the proper type.
 func HandleInteractionArgumentTypeFloat(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
-	if len(option.Value) <= 2 { // ""
+	if len(option.Value) == 0 {
 		return nil, nil
 	}
 
-	var argument string
+	var argument float64
 
 	err = json.Unmarshal(option.Value, &argument)
+	if err == nil {
+		return argument, nil
+	}
+
+	// Commands registered before floats were number options send them as strings.
+	var stringArgument string
+
+	err = json.Unmarshal(option.Value, &stringArgument)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
 	}
 
-	result, err := strconv.ParseFloat(argument, 64)
+	if stringArgument == "" {
+		return nil, nil
+	}
+
+	result, err := strconv.ParseFloat(stringArgument, 64)
 	if err != nil {
 		return nil, ErrBadFloatArgument
 	}
@@ -489,31 +639,177 @@ func HandleInteractionArgumentTypeFloat(ctx context.Context, sub *Subway, intera
 	return result, nil
 }
 
+// HandleInteractionArgumentTypeAttachment handles converting from an attachment
+// argument into a MessageAttachment type. Use .Attachment() within a command
+// to get the proper type.
+func HandleInteractionArgumentTypeAttachment(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	snowflake, err := getOptionSnowflake(option)
+	if err != nil {
+		return nil, err
+	}
+
+	result := getResolvedData(interaction).Attachments[snowflake]
+
+	if result.ID.IsNil() {
+		return nil, ErrAttachmentNotFound
+	}
+
+	return result, nil
+}
+
+// HandleInteractionArgumentTypeMentionable handles converting from a mentionable
+// argument into a Mentionable type. Use .Mentionable() within a command
+// to get the proper type.
+func HandleInteractionArgumentTypeMentionable(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	snowflake, err := getOptionSnowflake(option)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := getResolvedData(interaction)
+
+	var result Mentionable
+
+	if user, ok := resolved.Users[snowflake]; ok {
+		result.User = &user
+
+		if member, ok := resolved.Members[snowflake]; ok {
+			member.User = &user
+			result.Member = &member
+		}
+
+		return result, nil
+	}
+
+	if role, ok := resolved.Roles[snowflake]; ok {
+		result.Role = &role
+
+		return result, nil
+	}
+
+	if snowflake.IsNil() || interaction.GuildID == nil {
+		return nil, ErrMentionableNotFound
+	}
+
+	grpcContext := sub.NewGRPCContext(ctx)
+
+	member, err := sub.GRPCInterface.FetchMemberByID(grpcContext, *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch member: %w", err)
+	}
+
+	if member.User != nil && member.User.ID == snowflake {
+		result.User = member.User
+		result.Member = &member
+
+		return result, nil
+	}
+
+	role, err := sub.GRPCInterface.FetchRoleByID(grpcContext, *interaction.GuildID, snowflake)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch role: %w", err)
+	}
+
+	// Sandwich may return a different role, such as @everyone, if the role was not found.
+	if role.ID != snowflake {
+		return nil, ErrMentionableNotFound
+	}
+
+	result.Role = &role
+
+	return result, nil
+}
+
+// getOptionSnowflake returns the ID in an option value. Values can be an ID or a mention, so string
+// options from older command definitions and components can also be converted. If there is no ID,
+// an empty snowflake is returned.
+func getOptionSnowflake(option discord.InteractionDataOption) (discord.Snowflake, error) {
+	var argument string
+
+	err := json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	match := IDRegex.FindString(argument)
+	if match == "" {
+		matches := GenericMentionRegex.FindStringSubmatch(argument)
+		if len(matches) > 1 {
+			match = matches[1]
+		}
+	}
+
+	snowflakeID, _ := strconv.ParseInt(match, 10, 64)
+
+	return discord.Snowflake(snowflakeID), nil
+}
+
+// getResolvedData returns the resolved data of an interaction. Interactions without
+// resolved data, such as ones not from discord, return empty resolved data.
+func getResolvedData(interaction discord.Interaction) *discord.InteractionResolvedData {
+	if interaction.Data == nil || interaction.Data.Resolved == nil {
+		return &discord.InteractionResolvedData{}
+	}
+
+	return interaction.Data.Resolved
+}
+
 func NewInteractionConverters() *InteractionConverters {
 	converters := &InteractionConverters{
 		convertersMu: sync.RWMutex{},
 		Converters:   make(map[ArgumentType]*InteractionConverter),
 	}
 
-	converters.RegisterConverter(ArgumentTypeSnowflake, HandleInteractionArgumentTypeSnowflake, nil)
-	converters.RegisterConverter(ArgumentTypeMember, HandleInteractionArgumentTypeMember, nil)
-	converters.RegisterConverter(ArgumentTypeUser, HandleInteractionArgumentTypeUser, nil)
-	converters.RegisterConverter(ArgumentTypeTextChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeGuild, HandleInteractionArgumentTypeGuild, nil)
-	converters.RegisterConverter(ArgumentTypeRole, HandleInteractionArgumentTypeRole, nil)
-	converters.RegisterConverter(ArgumentTypeColour, HandleInteractionArgumentTypeColour, nil)
-	converters.RegisterConverter(ArgumentTypeVoiceChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeStageChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeEmoji, HandleInteractionArgumentTypeEmoji, nil)
-	converters.RegisterConverter(ArgumentTypePartialEmoji, HandleInteractionArgumentTypePartialEmoji, nil)
-	converters.RegisterConverter(ArgumentTypeCategoryChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeStoreChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeThread, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeGuildChannel, HandleInteractionArgumentTypeGuildChannel, nil)
-	converters.RegisterConverter(ArgumentTypeString, HandleInteractionArgumentTypeString, "")
-	converters.RegisterConverter(ArgumentTypeBool, HandleInteractionArgumentTypeBool, false)
-	converters.RegisterConverter(ArgumentTypeInt, HandleInteractionArgumentTypeInt, int64(0))
-	converters.RegisterConverter(ArgumentTypeFloat, HandleInteractionArgumentTypeFloat, float64(0))
+	for argumentType, options := range map[ArgumentType]InteractionConverterOptions{
+		ArgumentTypeSnowflake:       {Converter: HandleInteractionArgumentTypeSnowflake},
+		ArgumentTypeMember:          {Converter: HandleInteractionArgumentTypeMember, OptionType: discord.ApplicationCommandOptionTypeUser},
+		ArgumentTypeUser:            {Converter: HandleInteractionArgumentTypeUser, OptionType: discord.ApplicationCommandOptionTypeUser},
+		ArgumentTypeTextChannel:     newChannelConverterOptions(discord.ChannelTypeGuildText),
+		ArgumentTypeGuild:           {Converter: HandleInteractionArgumentTypeGuild},
+		ArgumentTypeRole:            {Converter: HandleInteractionArgumentTypeRole, OptionType: discord.ApplicationCommandOptionTypeRole},
+		ArgumentTypeColour:          {Converter: HandleInteractionArgumentTypeColour},
+		ArgumentTypeVoiceChannel:    newChannelConverterOptions(discord.ChannelTypeGuildVoice),
+		ArgumentTypeStageChannel:    newChannelConverterOptions(discord.ChannelTypeGuildStageVoice),
+		ArgumentTypeEmoji:           {Converter: HandleInteractionArgumentTypeEmoji},
+		ArgumentTypePartialEmoji:    {Converter: HandleInteractionArgumentTypePartialEmoji},
+		ArgumentTypeCategoryChannel: newChannelConverterOptions(discord.ChannelTypeGuildCategory),
+		ArgumentTypeStoreChannel:    newChannelConverterOptions(discord.ChannelTypeGuildStore),
+		ArgumentTypeThread:          newChannelConverterOptions(discord.ChannelTypeGuildPublicThread),
+		ArgumentTypeGuildChannel:    newChannelConverterOptions(),
+		ArgumentTypeString:          {Converter: HandleInteractionArgumentTypeString, DefaultValue: ""},
+		ArgumentTypeBool:            {Converter: HandleInteractionArgumentTypeBool, DefaultValue: false, OptionType: discord.ApplicationCommandOptionTypeBoolean},
+		ArgumentTypeInt:             {Converter: HandleInteractionArgumentTypeInt, DefaultValue: int64(0), OptionType: discord.ApplicationCommandOptionTypeInteger},
+		ArgumentTypeFloat:           {Converter: HandleInteractionArgumentTypeFloat, DefaultValue: float64(0), OptionType: discord.ApplicationCommandOptionTypeNumber},
+		ArgumentTypeAttachment:      {Converter: HandleInteractionArgumentTypeAttachment, OptionType: discord.ApplicationCommandOptionTypeAttachment},
+		ArgumentTypeMentionable:     {Converter: HandleInteractionArgumentTypeMentionable, OptionType: discord.ApplicationCommandOptionTypeMentionable},
+		ArgumentTypeDuration:        {Converter: HandleInteractionArgumentTypeDuration, DefaultValue: time.Duration(0)},
+		ArgumentTypeTimestamp:       {Converter: HandleInteractionArgumentTypeTimestamp},
+		ArgumentTypeTimezone:        {Converter: HandleInteractionArgumentTypeTimezone, Autocomplete: HandleInteractionAutocompleteTimezone},
+		ArgumentTypeMessage:         {Converter: HandleInteractionArgumentTypeMessage},
+		ArgumentTypeInvite:          {Converter: HandleInteractionArgumentTypeInvite},
+		ArgumentTypeWebhook:         {Converter: HandleInteractionArgumentTypeWebhook},
+	} {
+		converters.RegisterConverterWithOptions(argumentType, options)
+	}
 
 	return converters
 }
+
+func newChannelConverterOptions(channelTypes ...discord.ChannelType) InteractionConverterOptions {
+	return InteractionConverterOptions{
+		Converter:    HandleInteractionArgumentTypeGuildChannel,
+		OptionType:   discord.ApplicationCommandOptionTypeChannel,
+		ChannelTypes: channelTypes,
+	}
+}
+
+// defaultInteractionConverters are used to map commands that are not in a command tree with converters.
+var defaultInteractionConverters = NewInteractionConverters()
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	discord "github.com/WelcomerTeam/Discord/discord"
+)
+
+var (
+	MessageLinkRegex = regexp.MustCompile(`^https?://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/channels/([0-9]{15,20}|@me)/([0-9]{15,20})/([0-9]{15,20})/?$`)
+	MessageIDsRegex  = regexp.MustCompile(`^([0-9]{15,20})-([0-9]{15,20})$`)
+	InviteRegex      = regexp.MustCompile(`^(?:https?://)?(?:www\.)?(?:discord(?:app)?\.com/invite|discord\.gg)/([a-zA-Z0-9-]+)/?$`)
+	InviteCodeRegex  = regexp.MustCompile(`^[a-zA-Z0-9-]+$`)
+	WebhookRegex     = regexp.MustCompile(`^https?://(?:(?:ptb|canary)\.)?discord(?:app)?\.com/api/(?:v[0-9]+/)?webhooks/([0-9]{15,20})/([a-zA-Z0-9_-]+)/?$`)
+)
+
+// HandleInteractionArgumentTypeMessage handles converting from a string
+// argument into a Message type. Message links, "channelID-messageID" and
+// message IDs in the current channel are accepted. Messages outside the
+// guild of the interaction, or outside the channel in DMs, are not found,
+// but commands should check the user is able to view the channel. Use .Message() within a command to
+// get the proper type.
+func HandleInteractionArgumentTypeMessage(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	argument = strings.TrimSpace(argument)
+
+	var channelID, messageID int64
+
+	if matches := MessageLinkRegex.FindStringSubmatch(argument); len(matches) > 3 {
+		// Messages in DMs have a guild of @me.
+		guildID, _ := strconv.ParseInt(matches[1], 10, 64)
+
+		if (interaction.GuildID == nil && guildID != 0) || (interaction.GuildID != nil && int64(*interaction.GuildID) != guildID) {
+			return nil, ErrMessageNotFound
+		}
+
+		channelID, _ = strconv.ParseInt(matches[2], 10, 64)
+		messageID, _ = strconv.ParseInt(matches[3], 10, 64)
+	} else if matches := MessageIDsRegex.FindStringSubmatch(argument); len(matches) > 2 {
+		channelID, _ = strconv.ParseInt(matches[1], 10, 64)
+		messageID, _ = strconv.ParseInt(matches[2], 10, 64)
+	} else if match := IDRegex.FindString(argument); match == argument {
+		if interaction.ChannelID == nil {
+			return nil, ErrMessageNotFound
+		}
+
+		channelID = int64(*interaction.ChannelID)
+		messageID, _ = strconv.ParseInt(match, 10, 64)
+	} else {
+		return nil, ErrBadMessageArgument
+	}
+
+	if result, ok := getResolvedData(interaction).Messages[discord.Snowflake(messageID)]; ok && int64(result.ChannelID) == channelID {
+		return result, nil
+	}
+
+	session := sub.getSession(ctx)
+
+	// Messages do not include their guild, so the channel is checked instead.
+	if !isInteractionChannel(ctx, sub, session, interaction, discord.Snowflake(channelID)) {
+		return nil, ErrMessageNotFound
+	}
+
+	result, err := discord.GetChannelMessage(ctx, session, discord.Snowflake(channelID), discord.Snowflake(messageID))
+	if err != nil || result == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	return *result, nil
+}
+
+// isInteractionChannel returns if a channel is in the guild of the interaction. In DMs,
+// only the channel of the interaction is allowed.
+func isInteractionChannel(ctx context.Context, sub *Subway, session *discord.Session, interaction discord.Interaction, channelID discord.Snowflake) bool {
+	if interaction.GuildID == nil {
+		return interaction.ChannelID != nil && *interaction.ChannelID == channelID
+	}
+
+	if channel, ok := getResolvedData(interaction).Channels[channelID]; ok && channel.GuildID != nil {
+		return *channel.GuildID == *interaction.GuildID
+	}
+
+	// Sandwich only returns channels in the guild requested.
+	channel, err := sub.GRPCInterface.FetchChannelByID(sub.NewGRPCContext(ctx), *interaction.GuildID, channelID)
+	if err == nil && channel.ID == channelID {
+		return channel.GuildID == nil || *channel.GuildID == *interaction.GuildID
+	}
+
+	// Threads may not be cached by sandwich.
+	fetchedChannel, err := discord.GetChannel(ctx, session, channelID)
+	if err != nil || fetchedChannel == nil {
+		return false
+	}
+
+	return fetchedChannel.GuildID != nil && *fetchedChannel.GuildID == *interaction.GuildID
+}
+
+// HandleInteractionArgumentTypeInvite handles converting from a string
+// argument into an Invite type. Invite links and codes are accepted.
+// Use .Invite() within a command to get the proper type.
+func HandleInteractionArgumentTypeInvite(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	argument = strings.TrimSpace(argument)
+
+	code := argument
+
+	if matches := InviteRegex.FindStringSubmatch(argument); len(matches) > 1 {
+		code = matches[1]
+	} else if !InviteCodeRegex.MatchString(argument) {
+		return nil, ErrBadInviteArgument
+	}
+
+	withCounts := true
+	withExpiration := true
+
+	result, err := discord.GetInvite(ctx, sub.getSession(ctx), code, &withCounts, &withExpiration, nil)
+	if err != nil || result == nil {
+		return nil, ErrBadInviteArgument
+	}
+
+	return *result, nil
+}
+
+// HandleInteractionArgumentTypeWebhook handles converting from a string
+// argument into a Webhook type. Only the ID and token of the webhook are
+// set, the webhook is not fetched. Use .Webhook() within a command to get
+// the proper type.
+func HandleInteractionArgumentTypeWebhook(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	matches := WebhookRegex.FindStringSubmatch(strings.TrimSpace(argument))
+	if len(matches) < 3 {
+		return nil, ErrBadWebhookArgument
+	}
+
+	webhookID, _ := strconv.ParseInt(matches[1], 10, 64)
+
+	return discord.Webhook{
+		ID:    discord.Snowflake(webhookID),
+		Token: matches[2],
+	}, nil
+}
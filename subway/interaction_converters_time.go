@@ -0,0 +1,344 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	discord "github.com/WelcomerTeam/Discord/discord"
+)
+
+var (
+	DurationRegex          = regexp.MustCompile(`(\d+(?:\.\d+)?)\s*([a-z]+)`)
+	DurationSeparatorRegex = regexp.MustCompile(`^(?:\s|,|and)*$`)
+	DiscordTimestampRegex  = regexp.MustCompile(`^<t:(-?\d+)(?::[tdfr])?>$`)
+	TimeOfDayRegex         = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(?::(\d{2}))?\s*(am|pm)?$`)
+)
+
+// durationUnits are the units that can be used in duration arguments.
+var durationUnits = map[string]time.Duration{
+	"s": time.Second, "sec": time.Second, "secs": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "mins": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hrs": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour, "wk": 7 * 24 * time.Hour, "wks": 7 * 24 * time.Hour, "week": 7 * 24 * time.Hour, "weeks": 7 * 24 * time.Hour,
+}
+
+// timestampLayouts are the absolute formats accepted by timestamp arguments.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// timezoneNamesLower maps lowercase timezone names to their name, so timezones are case insensitive.
+var timezoneNamesLower = func() map[string]string {
+	names := make(map[string]string, len(timezoneNames))
+
+	for _, name := range timezoneNames {
+		names[strings.ToLower(name)] = name
+	}
+
+	return names
+}()
+
+// HandleInteractionArgumentTypeDuration handles converting from a string
+// argument into a Duration type, such as "1h30m" or "2 days". Use .Duration()
+// within a command to get the proper type.
+func HandleInteractionArgumentTypeDuration(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	result, ok := parseDuration(argument)
+	if !ok {
+		return nil, ErrBadDurationArgument
+	}
+
+	return result, nil
+}
+
+// HandleInteractionArgumentTypeTimestamp handles converting from a string
+// argument into a Time type. Absolute times such as "2024-01-02 15:04" or
+// discord timestamps, and relative times such as "tomorrow 9pm" or "in 2 hours"
+// are accepted. Times are in UTC, including days and times of day, so "tomorrow
+// 9pm" is 9pm UTC and not in the timezone of the user. Commands that need local
+// times should also ask for a timezone argument. Use .Timestamp() within a
+// command to get the proper type.
+func HandleInteractionArgumentTypeTimestamp(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	result, ok := parseTimestamp(argument, time.Now().UTC())
+	if !ok {
+		return nil, ErrBadTimestampArgument
+	}
+
+	return result, nil
+}
+
+// HandleInteractionArgumentTypeTimezone handles converting from a string
+// argument into a Location type, such as "Europe/London". Timezones are
+// loaded from the tz database of the system. Images without one, such as
+// scratch or distroless images, should import time/tzdata in the program,
+// otherwise every timezone is rejected. Use .Timezone() within a command to
+// get the proper type.
+func HandleInteractionArgumentTypeTimezone(ctx context.Context, sub *Subway, interaction discord.Interaction, option discord.InteractionDataOption) (out interface{}, err error) {
+	if len(option.Value) <= 2 { // ""
+		return nil, nil
+	}
+
+	var argument string
+
+	err = json.Unmarshal(option.Value, &argument)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal option value: %w", err)
+	}
+
+	name := strings.ReplaceAll(strings.TrimSpace(argument), " ", "_")
+
+	if canonicalName, ok := timezoneNamesLower[strings.ToLower(name)]; ok {
+		name = canonicalName
+	}
+
+	// Local is not a timezone, but is accepted by LoadLocation.
+	if name == "" || name == "Local" {
+		return nil, ErrBadTimezoneArgument
+	}
+
+	result, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrBadTimezoneArgument
+	}
+
+	return result, nil
+}
+
+// HandleInteractionAutocompleteTimezone suggests timezones matching the focused argument.
+// Timezones starting with the query, or whose city starts with it, are suggested first.
+func HandleInteractionAutocompleteTimezone(ctx context.Context, sub *Subway, interaction discord.Interaction) ([]discord.ApplicationCommandOptionChoice, error) {
+	var query string
+
+	for _, rawOption := range GetRawOptionsFromContext(ctx) {
+		if rawOption.Focused {
+			_ = json.Unmarshal(rawOption.Value, &query)
+
+			break
+		}
+	}
+
+	query = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(query), " ", "_"))
+
+	prefixMatches := make([]string, 0, maxCommandChoices)
+	otherMatches := make([]string, 0, maxCommandChoices)
+
+	for _, name := range timezoneNames {
+		if len(prefixMatches) == maxCommandChoices {
+			break
+		}
+
+		lowerName := strings.ToLower(name)
+
+		switch {
+		case strings.HasPrefix(lowerName, query) || strings.HasPrefix(lowerName[strings.LastIndex(lowerName, "/")+1:], query):
+			prefixMatches = append(prefixMatches, name)
+		case len(otherMatches) < maxCommandChoices && strings.Contains(lowerName, query):
+			otherMatches = append(otherMatches, name)
+		}
+	}
+
+	matches := append(prefixMatches, otherMatches...)
+	choices := make([]discord.ApplicationCommandOptionChoice, 0, maxCommandChoices)
+
+	for _, name := range matches[:min(len(matches), maxCommandChoices)] {
+		value, err := json.Marshal(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal timezone: %w", err)
+		}
+
+		choices = append(choices, discord.ApplicationCommandOptionChoice{
+			Name:  strings.ReplaceAll(name, "_", " "),
+			Value: value,
+		})
+	}
+
+	return choices, nil
+}
+
+// parseDuration parses a duration in the format of time.ParseDuration, or a list of
+// numbers and units, such as "2 days, 3 hours". Negative durations are not accepted.
+func parseDuration(argument string) (time.Duration, bool) {
+	argument = strings.ToLower(strings.TrimSpace(argument))
+
+	duration, err := time.ParseDuration(argument)
+	if err == nil {
+		return duration, duration >= 0
+	}
+
+	matches := DurationRegex.FindAllStringSubmatchIndex(argument, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+
+	lastEnd := 0
+
+	for _, match := range matches {
+		// Only separators are allowed between each number and unit.
+		if !DurationSeparatorRegex.MatchString(argument[lastEnd:match[0]]) {
+			return 0, false
+		}
+
+		value, err := strconv.ParseFloat(argument[match[2]:match[3]], 64)
+		if err != nil {
+			return 0, false
+		}
+
+		unit, ok := durationUnits[argument[match[4]:match[5]]]
+		if !ok {
+			return 0, false
+		}
+
+		// Durations larger than the maximum duration would overflow and become negative.
+		term := value * float64(unit)
+		if term >= math.MaxInt64 || time.Duration(term) > math.MaxInt64-duration {
+			return 0, false
+		}
+
+		duration += time.Duration(term)
+		lastEnd = match[1]
+	}
+
+	return duration, DurationSeparatorRegex.MatchString(argument[lastEnd:])
+}
+
+// parseTimestamp parses an absolute or relative time. Relative times are relative to now.
+func parseTimestamp(argument string, now time.Time) (time.Time, bool) {
+	argument = strings.ToLower(strings.Join(strings.Fields(argument), " "))
+
+	if matches := DiscordTimestampRegex.FindStringSubmatch(argument); len(matches) > 1 {
+		seconds, err := strconv.ParseInt(matches[1], 10, 64)
+
+		return time.Unix(seconds, 0).UTC(), err == nil
+	}
+
+	for _, layout := range timestampLayouts {
+		result, err := time.Parse(layout, strings.ToUpper(argument))
+		if err == nil {
+			return result.UTC(), true
+		}
+	}
+
+	switch {
+	case argument == "now":
+		return now, true
+	case strings.HasPrefix(argument, "in "):
+		duration, ok := parseDuration(strings.TrimPrefix(argument, "in "))
+
+		return now.Add(duration), ok
+	case strings.HasSuffix(argument, " ago"):
+		duration, ok := parseDuration(strings.TrimSuffix(argument, " ago"))
+
+		return now.Add(-duration), ok
+	}
+
+	day, timeOfDay, _ := strings.Cut(argument, " ")
+	year, month, date := now.Date()
+
+	switch day {
+	case "today":
+	case "tomorrow":
+		date++
+	case "yesterday":
+		date--
+	default:
+		// Times without a day are the next time it is that time.
+		hour, minute, second, ok := parseTimeOfDay(argument)
+		if !ok {
+			return time.Time{}, false
+		}
+
+		result := time.Date(year, month, date, hour, minute, second, 0, now.Location())
+		if result.Before(now) {
+			result = result.AddDate(0, 0, 1)
+		}
+
+		return result, true
+	}
+
+	if timeOfDay == "" {
+		return time.Date(year, month, date, 0, 0, 0, 0, now.Location()), true
+	}
+
+	hour, minute, second, ok := parseTimeOfDay(strings.TrimPrefix(timeOfDay, "at "))
+	if !ok {
+		return time.Time{}, false
+	}
+
+	return time.Date(year, month, date, hour, minute, second, 0, now.Location()), true
+}
+
+// parseTimeOfDay parses a time such as "9pm", "9:30 am", "21:00" or "noon".
+func parseTimeOfDay(argument string) (hour, minute, second int, ok bool) {
+	switch argument {
+	case "noon":
+		return 12, 0, 0, true
+	case "midnight":
+		return 0, 0, 0, true
+	}
+
+	matches := TimeOfDayRegex.FindStringSubmatch(argument)
+	if len(matches) == 0 {
+		return 0, 0, 0, false
+	}
+
+	hour, _ = strconv.Atoi(matches[1])
+	minute, _ = strconv.Atoi(matches[2])
+	second, _ = strconv.Atoi(matches[3])
+
+	switch matches[4] {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, 0, false
+		}
+
+		hour %= 12
+
+		if matches[4] == "pm" {
+			hour += 12
+		}
+	default:
+		// A number on its own is not a time.
+		if matches[2] == "" {
+			return 0, 0, 0, false
+		}
+	}
+
+	if hour > 23 || minute > 59 || second > 59 {
+		return 0, 0, 0, false
+	}
+
+	return hour, minute, second, true
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		argument string
+		want     time.Duration
+		ok       bool
+	}{
+		{argument: "1h30m", want: 90 * time.Minute, ok: true},
+		{argument: "90s", want: 90 * time.Second, ok: true},
+		{argument: "2 days", want: 48 * time.Hour, ok: true},
+		{argument: "2 Days, 3 hours and 5 mins", want: 51*time.Hour + 5*time.Minute, ok: true},
+		{argument: "1.5 weeks", want: 252 * time.Hour, ok: true},
+		{argument: "  1 hr  ", want: time.Hour, ok: true},
+		{argument: "-1h", ok: false},
+		{argument: "9999999999h", ok: false},
+		{argument: "20000000 weeks", ok: false},
+		{argument: "100000 weeks 100000 weeks 100000 weeks 100000 weeks 100000 weeks", ok: false},
+		{argument: "", ok: false},
+		{argument: "soon", ok: false},
+		{argument: "2 fortnights", ok: false},
+		{argument: "2 days or so", ok: false},
+		{argument: "about 2 days", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.argument, func(t *testing.T) {
+			got, ok := parseDuration(tt.argument)
+			if ok != tt.ok {
+				t.Fatalf("parseDuration(%q) ok = %t, want %t", tt.argument, ok, tt.ok)
+			}
+
+			if ok && got != tt.want {
+				t.Errorf("parseDuration(%q) = %s, want %s", tt.argument, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		argument string
+		want     time.Time
+		ok       bool
+	}{
+		{argument: "now", want: now, ok: true},
+		{argument: "in 2 hours", want: now.Add(2 * time.Hour), ok: true},
+		{argument: "3 days ago", want: now.Add(-72 * time.Hour), ok: true},
+		{argument: "tomorrow 9pm", want: time.Date(2024, time.March, 11, 21, 0, 0, 0, time.UTC), ok: true},
+		{argument: "Tomorrow at 9:30 AM", want: time.Date(2024, time.March, 11, 9, 30, 0, 0, time.UTC), ok: true},
+		{argument: "yesterday noon", want: time.Date(2024, time.March, 9, 12, 0, 0, 0, time.UTC), ok: true},
+		{argument: "today", want: time.Date(2024, time.March, 10, 0, 0, 0, 0, time.UTC), ok: true},
+		{argument: "9pm", want: time.Date(2024, time.March, 10, 21, 0, 0, 0, time.UTC), ok: true},
+		{argument: "9am", want: time.Date(2024, time.March, 11, 9, 0, 0, 0, time.UTC), ok: true},
+		{argument: "2024-05-01", want: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), ok: true},
+		{argument: "2024-05-01 18:45", want: time.Date(2024, time.May, 1, 18, 45, 0, 0, time.UTC), ok: true},
+		{argument: "2024-05-01T18:45:30", want: time.Date(2024, time.May, 1, 18, 45, 30, 0, time.UTC), ok: true},
+		{argument: "2024-05-01T18:45:30+02:00", want: time.Date(2024, time.May, 1, 16, 45, 30, 0, time.UTC), ok: true},
+		{argument: "<t:1700000000:R>", want: time.Unix(1700000000, 0).UTC(), ok: true},
+		{argument: "", ok: false},
+		{argument: "next week", ok: false},
+		{argument: "in a while", ok: false},
+		{argument: "tomorrow 25:00", ok: false},
+		{argument: "13pm", ok: false},
+		{argument: "9", ok: false},
+		{argument: "2024-13-01", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.argument, func(t *testing.T) {
+			got, ok := parseTimestamp(tt.argument, now)
+			if ok != tt.ok {
+				t.Fatalf("parseTimestamp(%q) ok = %t, want %t", tt.argument, ok, tt.ok)
+			}
+
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("parseTimestamp(%q) = %s, want %s", tt.argument, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// Interaction tokens are valid for 15 minutes, after which a duplicate
+// delivery is not able to be responded to.
+const interactionTokenLifetime = 15 * time.Minute
+
+// Discord stops waiting for the response of an interaction after 3 seconds, so
+// duplicate deliveries do not wait for the first delivery any longer than this.
+const interactionResponseTimeout = 3 * time.Second
+
+// DedupeStore tracks interactions that have been processed, so a duplicate
+// delivery of an interaction does not run its handler again.
+type DedupeStore interface {
+	// Claim marks an interaction as being processed. Returns false if the
+	// interaction has already been claimed by an earlier delivery.
+	Claim(ctx context.Context, interactionID discord.Snowflake, ttl time.Duration) (bool, error)
+
+	// Complete stores the response for a claimed interaction and releases
+	// any deliveries waiting on it.
+	Complete(ctx context.Context, interactionID discord.Snowflake, response *discord.InteractionResponse) error
+
+	// Wait blocks until a claimed interaction has completed and returns its response.
+	Wait(ctx context.Context, interactionID discord.Snowflake) (*discord.InteractionResponse, error)
+}
+
+type memoryDedupeEntry struct {
+	done      chan struct{}
+	response  *discord.InteractionResponse
+	expiresAt time.Time
+}
+
+// Maximum number of interactions tracked by a MemoryDedupeStore by default.
+const defaultMemoryDedupeStoreSize = 10000
+
+// MemoryDedupeStore is an in-memory DedupeStore. This only dedupes
+// deliveries that are received by the same process. Once the store is full,
+// the oldest interactions are no longer deduped.
+type MemoryDedupeStore struct {
+	entriesMu sync.Mutex
+	entries   map[discord.Snowflake]*memoryDedupeEntry
+
+	maxEntries int
+	nextPurge  time.Time
+}
+
+// NewMemoryDedupeStore creates a new in-memory DedupeStore.
+func NewMemoryDedupeStore() *MemoryDedupeStore {
+	return NewMemoryDedupeStoreWithSize(defaultMemoryDedupeStoreSize)
+}
+
+// NewMemoryDedupeStoreWithSize creates a new in-memory DedupeStore that tracks at most maxEntries interactions.
+func NewMemoryDedupeStoreWithSize(maxEntries int) *MemoryDedupeStore {
+	return &MemoryDedupeStore{
+		entriesMu:  sync.Mutex{},
+		entries:    make(map[discord.Snowflake]*memoryDedupeEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+func (ds *MemoryDedupeStore) Claim(_ context.Context, interactionID discord.Snowflake, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	ds.entriesMu.Lock()
+	defer ds.entriesMu.Unlock()
+
+	if now.After(ds.nextPurge) {
+		ds.purge(now)
+	}
+
+	if entry, ok := ds.entries[interactionID]; ok && entry.expiresAt.After(now) {
+		return false, nil
+	}
+
+	if ds.maxEntries > 0 && len(ds.entries) >= ds.maxEntries {
+		ds.purge(now)
+
+		if len(ds.entries) >= ds.maxEntries {
+			ds.evictOldest()
+		}
+	}
+
+	ds.entries[interactionID] = &memoryDedupeEntry{
+		done:      make(chan struct{}),
+		expiresAt: now.Add(ttl),
+	}
+
+	return true, nil
+}
+
+func (ds *MemoryDedupeStore) Complete(_ context.Context, interactionID discord.Snowflake, response *discord.InteractionResponse) error {
+	ds.entriesMu.Lock()
+	defer ds.entriesMu.Unlock()
+
+	entry, ok := ds.entries[interactionID]
+	if !ok {
+		return nil
+	}
+
+	select {
+	case <-entry.done:
+	default:
+		entry.response = withoutResponseFiles(response)
+
+		close(entry.done)
+	}
+
+	return nil
+}
+
+func (ds *MemoryDedupeStore) Wait(ctx context.Context, interactionID discord.Snowflake) (*discord.InteractionResponse, error) {
+	ds.entriesMu.Lock()
+	entry, ok := ds.entries[interactionID]
+	ds.entriesMu.Unlock()
+
+	if !ok {
+		return nil, nil
+	}
+
+	select {
+	case <-entry.done:
+		ds.entriesMu.Lock()
+		defer ds.entriesMu.Unlock()
+
+		return entry.response, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// purge removes expired entries. entriesMu must be held.
+func (ds *MemoryDedupeStore) purge(now time.Time) {
+	for interactionID, entry := range ds.entries {
+		if entry.expiresAt.Before(now) {
+			delete(ds.entries, interactionID)
+		}
+	}
+
+	ds.nextPurge = now.Add(time.Minute)
+}
+
+// evictOldest removes the interaction that expires first. entriesMu must be held.
+func (ds *MemoryDedupeStore) evictOldest() {
+	var oldestID discord.Snowflake
+
+	var oldest *memoryDedupeEntry
+
+	for interactionID, entry := range ds.entries {
+		if oldest == nil || entry.expiresAt.Before(oldest.expiresAt) {
+			oldestID, oldest = interactionID, entry
+		}
+	}
+
+	if oldest != nil {
+		delete(ds.entries, oldestID)
+	}
+}
+
+// withoutResponseFiles returns the response without its files, so they are not held whilst
+// waiting for duplicates. Duplicates are sent the response without the files.
+func withoutResponseFiles(response *discord.InteractionResponse) *discord.InteractionResponse {
+	if response == nil || response.Data == nil || len(response.Data.Files) == 0 {
+		return response
+	}
+
+	data := *response.Data
+	data.Files = nil
+
+	return &discord.InteractionResponse{
+		Type: response.Type,
+		Data: &data,
+	}
+}
+
+// processInteractionOnce processes an interaction, unless it is a duplicate delivery of an
+// interaction that has already been processed. Duplicates receive the response of the first
+// delivery, waiting for it to finish if it is still being processed. Duplicates only wait
+// until the response would be deferred, after which no response is returned.
+func (sub *Subway) processInteractionOnce(ctx context.Context, interaction discord.Interaction, start time.Time) interactionResult {
+	if sub.DedupeStore == nil {
+		response, err := sub.processInteraction(ctx, interaction, start)
+
+		return interactionResult{response: response, err: err}
+	}
+
+	claimed, err := sub.DedupeStore.Claim(ctx, interaction.ID, interactionTokenLifetime)
+	if err != nil {
+		// If the store is unavailable, we would rather process the interaction than drop it.
+		sub.getLogger(ctx).Warn().Err(err).Msg("Failed to claim interaction")
+
+		claimed = true
+	}
+
+	if !claimed {
+		sub.getLogger(ctx).Debug().Msg("Received duplicate interaction")
+
+		subwayDuplicateInteractionTotal.Add(1)
+
+		waitTimeout := interactionResponseTimeout
+		if sub.deferResponseAfter > 0 {
+			waitTimeout = min(waitTimeout, sub.deferResponseAfter)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, waitTimeout)
+		defer cancel()
+
+		response, err := sub.DedupeStore.Wait(waitCtx, interaction.ID)
+
+		return interactionResult{response: response, err: err, duplicate: true}
+	}
+
+	response, err := sub.processInteraction(ctx, interaction, start)
+
+	completeErr := sub.DedupeStore.Complete(ctx, interaction.ID, response)
+	if completeErr != nil {
+		sub.getLogger(ctx).Warn().Err(completeErr).Msg("Failed to store interaction response")
+	}
+
+	return interactionResult{response: response, err: err}
+}
@@ -0,0 +1,186 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+func TestMemoryDedupeStore(t *testing.T) {
+	response := &discord.InteractionResponse{
+		Type: discord.InteractionCallbackTypeChannelMessageSource,
+		Data: &discord.InteractionCallbackData{
+			Content: "pong",
+			Files:   []discord.File{{Name: "pong.png"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		maxEntries int
+		ttl        time.Duration
+		claims     []discord.Snowflake
+		wantClaims []bool
+	}{
+		{
+			name:       "duplicate",
+			maxEntries: 10,
+			ttl:        time.Minute,
+			claims:     []discord.Snowflake{1, 1, 2},
+			wantClaims: []bool{true, false, true},
+		},
+		{
+			name:       "expired",
+			maxEntries: 10,
+			ttl:        -time.Second,
+			claims:     []discord.Snowflake{1, 1},
+			wantClaims: []bool{true, true},
+		},
+		{
+			name:       "oldest evicted once full",
+			maxEntries: 2,
+			ttl:        time.Minute,
+			claims:     []discord.Snowflake{1, 2, 3, 1, 3},
+			wantClaims: []bool{true, true, true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryDedupeStoreWithSize(tt.maxEntries)
+
+			for i, interactionID := range tt.claims {
+				claimed, err := store.Claim(ctx, interactionID, tt.ttl)
+				if err != nil {
+					t.Fatalf("Claim(%d) error = %v", interactionID, err)
+				}
+
+				if claimed != tt.wantClaims[i] {
+					t.Fatalf("Claim(%d) #%d = %t, want %t", interactionID, i, claimed, tt.wantClaims[i])
+				}
+
+				// Keep the order entries expire in the same as the order they are claimed.
+				time.Sleep(time.Millisecond)
+			}
+
+			if len(store.entries) > tt.maxEntries {
+				t.Fatalf("store has %d entries, want at most %d", len(store.entries), tt.maxEntries)
+			}
+
+			interactionID := tt.claims[len(tt.claims)-1]
+
+			if err := store.Complete(ctx, interactionID, response); err != nil {
+				t.Fatalf("Complete() error = %v", err)
+			}
+
+			got, err := store.Wait(ctx, interactionID)
+			if err != nil {
+				t.Fatalf("Wait() error = %v", err)
+			}
+
+			if got == nil || got.Data.Content != response.Data.Content || len(got.Data.Files) != 0 {
+				t.Fatalf("Wait() = %+v, want the response without files", got)
+			}
+
+			if len(response.Data.Files) != 1 {
+				t.Fatal("Complete() removed the files of the original response")
+			}
+		})
+	}
+}
+
+func TestMemoryDedupeStoreWaitTimeout(t *testing.T) {
+	store := NewMemoryDedupeStore()
+
+	if _, err := store.Claim(context.Background(), 1, time.Minute); err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := store.Wait(ctx, 1); err == nil {
+		t.Fatal("Wait() returned before the interaction completed")
+	}
+}
+
+func TestProcessInteractionOnce(t *testing.T) {
+	const deliveries = 20
+
+	sub := newTestSubway(t, SubwayOptions{})
+
+	var calls atomic.Int32
+
+	handling := make(chan struct{})
+	finish := make(chan struct{})
+
+	sub.Commands.MustAddInteractionCommand(&InteractionCommandable{
+		Name:        "ping",
+		Description: "ping",
+		Handler: func(context.Context, *Subway, discord.Interaction) (*discord.InteractionResponse, error) {
+			if calls.Add(1) == 1 {
+				close(handling)
+			}
+
+			<-finish
+
+			return &discord.InteractionResponse{
+				Type: discord.InteractionCallbackTypeChannelMessageSource,
+				Data: &discord.InteractionCallbackData{Content: "pong"},
+			}, nil
+		},
+	})
+
+	interaction := newTestCommandInteraction("ping")
+	interaction.ID = 1
+
+	results := make([]interactionResult, deliveries)
+
+	var wg sync.WaitGroup
+
+	for i := range deliveries {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			results[i] = sub.processInteractionOnce(context.Background(), interaction, time.Now())
+		}()
+	}
+
+	// Let the duplicates arrive whilst the first delivery is still being handled.
+	<-handling
+	time.Sleep(10 * time.Millisecond)
+	close(finish)
+
+	wg.Wait()
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("handler ran %d times, want 1", got)
+	}
+
+	var duplicates int
+
+	for _, result := range results {
+		if result.err != nil {
+			t.Fatalf("processInteractionOnce() error = %v", result.err)
+		}
+
+		if result.response == nil || result.response.Data.Content != "pong" {
+			t.Fatalf("processInteractionOnce() response = %+v, want pong", result.response)
+		}
+
+		if result.duplicate {
+			duplicates++
+		}
+	}
+
+	if duplicates != deliveries-1 {
+		t.Fatalf("duplicates = %d, want %d", duplicates, deliveries-1)
+	}
+}
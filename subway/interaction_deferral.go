@@ -0,0 +1,144 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// InteractionAcknowledgement tracks if an interaction has been responded to
+// outside of the handler response, such as when opening a modal.
+type InteractionAcknowledgement struct {
+	acknowledged atomic.Bool
+}
+
+// MarkInteractionAcknowledged marks the interaction in the context as having been responded to
+// through the REST API. Subway will then not defer or respond to the interaction itself.
+func MarkInteractionAcknowledged(ctx context.Context) {
+	acknowledgement, ok := ctx.Value(InteractionAcknowledgementKey).(*InteractionAcknowledgement)
+	if ok {
+		acknowledgement.acknowledged.Store(true)
+	}
+}
+
+// IsInteractionAcknowledged returns if the interaction in the context has been responded to
+// through the REST API.
+func IsInteractionAcknowledged(ctx context.Context) bool {
+	acknowledgement, ok := ctx.Value(InteractionAcknowledgementKey).(*InteractionAcknowledgement)
+
+	return ok && acknowledgement.acknowledged.Load()
+}
+
+// getDeferredResponse returns the response to send when a handler has not returned in time.
+// Returns nil if deferring is disabled or the interaction does not support being deferred.
+func (sub *Subway) getDeferredResponse(ctx context.Context, interaction discord.Interaction) *discord.InteractionResponse {
+	if sub.deferResponseAfter <= 0 {
+		return nil
+	}
+
+	switch interaction.Type {
+	case discord.InteractionTypeApplicationCommand:
+		if sub.isEphemeralDefer(ctx, interaction) {
+			return &discord.InteractionResponse{
+				Type: discord.InteractionCallbackTypeDeferredChannelMessageSource,
+				Data: &discord.InteractionCallbackData{
+					Flags: uint32(discord.MessageFlagEphemeral),
+				},
+			}
+		}
+
+		return &discord.InteractionResponse{
+			Type: discord.InteractionCallbackTypeDeferredChannelMessageSource,
+		}
+	case discord.InteractionTypeModalSubmit:
+		return &discord.InteractionResponse{
+			Type: discord.InteractionCallbackTypeDeferredChannelMessageSource,
+		}
+	case discord.InteractionTypeMessageComponent:
+		return &discord.InteractionResponse{
+			Type: discord.InteractionCallbackTypeDeferredUpdateMessage,
+		}
+	default:
+		return nil
+	}
+}
+
+// isEphemeralDefer returns true if the command being invoked, or any of its parents, has EphemeralDefer set.
+func (sub *Subway) isEphemeralDefer(ctx context.Context, interaction discord.Interaction) bool {
+	if interaction.Data == nil {
+		return false
+	}
+
+	commandTree := constructCommandTree(interaction.Data.Options, []string{interaction.Data.Name})
+
+	for command := sub.getCommands(ctx).GetCommand(strings.Join(commandTree, " ")); command != nil; command = command.parent {
+		if command.EphemeralDefer {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliverDeferredResponse delivers the response of a handler for an interaction that has
+// already been deferred. The response is sent as an edit to the original response, or as a
+// followup message if the handler created a new message for a deferred message update.
+// As the deferred response has already been sent, message flags such as ephemeral are not
+// able to be changed, so commands with ephemeral responses should set EphemeralDefer.
+func (sub *Subway) deliverDeferredResponse(ctx context.Context, interaction discord.Interaction, deferredType discord.InteractionCallbackType, response *discord.InteractionResponse, err error) {
+	if response == nil {
+		// Remove the loading state, as there is nothing to replace it with.
+		if deferredType == discord.InteractionCallbackTypeDeferredChannelMessageSource {
+			deleteErr := interaction.DeleteOriginalResponse(ctx, sub.getEmptySession(ctx))
+			if deleteErr != nil {
+				sub.getLogger(ctx).Warn().Err(deleteErr).Msg("Failed to delete deferred response")
+			}
+		}
+
+		return
+	}
+
+	messageParams := webhookMessageParamsFromResponse(response)
+
+	switch response.Type {
+	case discord.InteractionCallbackTypeDeferredChannelMessageSource,
+		discord.InteractionCallbackTypeDeferredUpdateMessage:
+		// The handler has deferred itself, so there is nothing to deliver.
+	case discord.InteractionCallbackTypeChannelMessageSource:
+		if deferredType == discord.InteractionCallbackTypeDeferredUpdateMessage {
+			_, err = interaction.SendFollowup(ctx, sub.getEmptySession(ctx), messageParams)
+		} else {
+			_, err = interaction.EditOriginalResponse(ctx, sub.getEmptySession(ctx), messageParams)
+		}
+	case discord.InteractionCallbackTypeUpdateMessage:
+		_, err = interaction.EditOriginalResponse(ctx, sub.getEmptySession(ctx), messageParams)
+	default:
+		sub.getLogger(ctx).Warn().
+			Int("response_type", int(response.Type)).
+			Msg("Response type cannot be delivered after the interaction has been deferred")
+	}
+
+	if err != nil {
+		sub.getLogger(ctx).Error().Err(err).Msg("Failed to deliver deferred response")
+	}
+}
+
+// webhookMessageParamsFromResponse converts an interaction response into the
+// parameters used to edit or followup an interaction.
+func webhookMessageParamsFromResponse(response *discord.InteractionResponse) discord.WebhookMessageParams {
+	if response.Data == nil {
+		return discord.WebhookMessageParams{}
+	}
+
+	return discord.WebhookMessageParams{
+		Content:         response.Data.Content,
+		Embeds:          response.Data.Embeds,
+		AllowedMentions: response.Data.AllowedMentions,
+		Components:      response.Data.Components,
+		Files:           response.Data.Files,
+		Attachments:     response.Data.Attachments,
+		TTS:             response.Data.TTS,
+	}
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"container/heap"
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// DefaultBusyResponse is sent when an interaction has been waiting in the queue for too long.
+var DefaultBusyResponse = &discord.InteractionResponse{
+	Type: discord.InteractionCallbackTypeChannelMessageSource,
+	Data: &discord.InteractionCallbackData{
+		Content: "We are currently busy, please try again in a moment.",
+		Flags:   uint32(discord.MessageFlagEphemeral),
+	},
+}
+
+type limiterWaiter struct {
+	ready    chan struct{}
+	priority int
+	sequence uint64
+	index    int
+}
+
+// limiterQueue is a heap of waiters, ordered by highest priority then arrival.
+type limiterQueue []*limiterWaiter
+
+func (lq limiterQueue) Len() int { return len(lq) }
+
+func (lq limiterQueue) Less(i, j int) bool {
+	if lq[i].priority != lq[j].priority {
+		return lq[i].priority > lq[j].priority
+	}
+
+	return lq[i].sequence < lq[j].sequence
+}
+
+func (lq limiterQueue) Swap(i, j int) {
+	lq[i], lq[j] = lq[j], lq[i]
+	lq[i].index = i
+	lq[j].index = j
+}
+
+func (lq *limiterQueue) Push(x interface{}) {
+	waiter, _ := x.(*limiterWaiter)
+	waiter.index = len(*lq)
+	*lq = append(*lq, waiter)
+}
+
+func (lq *limiterQueue) Pop() interface{} {
+	old := *lq
+	n := len(old)
+	waiter := old[n-1]
+	old[n-1] = nil
+	waiter.index = -1
+	*lq = old[:n-1]
+
+	return waiter
+}
+
+// concurrencyLimiter bounds how many interactions are processed at once. Interactions
+// waiting for a slot are served by priority, then in the order they arrived.
+type concurrencyLimiter struct {
+	mu       sync.Mutex
+	limit    int
+	active   int
+	sequence uint64
+	waiters  limiterQueue
+}
+
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	return &concurrencyLimiter{
+		mu:      sync.Mutex{},
+		limit:   limit,
+		waiters: make(limiterQueue, 0),
+	}
+}
+
+// acquire waits for a slot until the timeout has passed. Returns false if no slot was acquired.
+func (cl *concurrencyLimiter) acquire(ctx context.Context, priority int, timeout time.Duration) bool {
+	cl.mu.Lock()
+
+	if cl.active < cl.limit {
+		cl.active++
+		cl.mu.Unlock()
+
+		return true
+	}
+
+	cl.sequence++
+
+	waiter := &limiterWaiter{
+		ready:    make(chan struct{}),
+		priority: priority,
+		sequence: cl.sequence,
+	}
+
+	heap.Push(&cl.waiters, waiter)
+	subwayInteractionQueueDepth.Set(float64(cl.waiters.Len()))
+
+	cl.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-waiter.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	// The slot may have been handed over whilst we were timing out.
+	if waiter.index < 0 {
+		cl.releaseLocked()
+
+		return false
+	}
+
+	heap.Remove(&cl.waiters, waiter.index)
+	subwayInteractionQueueDepth.Set(float64(cl.waiters.Len()))
+
+	return false
+}
+
+// release frees a slot, handing it over to the next waiter if there is one.
+func (cl *concurrencyLimiter) release() {
+	cl.mu.Lock()
+	cl.releaseLocked()
+	cl.mu.Unlock()
+}
+
+func (cl *concurrencyLimiter) releaseLocked() {
+	if cl.waiters.Len() > 0 {
+		waiter, _ := heap.Pop(&cl.waiters).(*limiterWaiter)
+		subwayInteractionQueueDepth.Set(float64(cl.waiters.Len()))
+
+		close(waiter.ready)
+
+		return
+	}
+
+	cl.active--
+}
+
+// acquireInteractionSlot waits for the interaction to be allowed to process. Returns false if
+// the queue timeout has passed, in which case the busy response should be sent instead.
+func (sub *Subway) acquireInteractionSlot(ctx context.Context, interaction discord.Interaction) (release func(), ok bool) {
+	if sub.limiter == nil {
+		return func() {}, true
+	}
+
+	if !sub.limiter.acquire(ctx, sub.getInteractionPriority(ctx, interaction), sub.queueTimeout) {
+		return nil, false
+	}
+
+	return sub.limiter.release, true
+}
+
+// getInteractionPriority returns the priority of the command being invoked. Commands without
+// a priority inherit the priority of their parent.
+func (sub *Subway) getInteractionPriority(ctx context.Context, interaction discord.Interaction) int {
+	if interaction.Data == nil || (interaction.Type != discord.InteractionTypeApplicationCommand &&
+		interaction.Type != discord.InteractionTypeApplicationCommandAutocomplete) {
+		return 0
+	}
+
+	commandTree := constructCommandTree(interaction.Data.Options, []string{interaction.Data.Name})
+
+	for command := sub.getCommands(ctx).GetCommand(strings.Join(commandTree, " ")); command != nil; command = command.parent {
+		if command.Priority != 0 {
+			return command.Priority
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,216 @@
+package internal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+	"github.com/rs/zerolog"
+)
+
+func newTestSubway(t *testing.T, options SubwayOptions) *Subway {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	options.Logger = zerolog.Nop()
+
+	sub, err := NewSubway(ctx, options)
+	if err != nil {
+		t.Fatalf("NewSubway() error = %v", err)
+	}
+
+	return sub
+}
+
+func newTestCommandInteraction(name string) discord.Interaction {
+	return discord.Interaction{
+		Type: discord.InteractionTypeApplicationCommand,
+		Data: &discord.InteractionData{Name: name},
+	}
+}
+
+// waitForQueueLength waits until the limiter has the number of waiters.
+func waitForQueueLength(t *testing.T, limiter *concurrencyLimiter, length int) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+
+	for time.Now().Before(deadline) {
+		limiter.mu.Lock()
+		queued := limiter.waiters.Len()
+		limiter.mu.Unlock()
+
+		if queued == length {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("queue did not reach %d waiters", length)
+}
+
+func TestAcquireInteractionSlotPriority(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands []string
+		want     []string
+	}{
+		{
+			name:     "higher priority first",
+			commands: []string{"low", "high", "normal"},
+			want:     []string{"high", "low", "normal"},
+		},
+		{
+			name:     "same priority in arrival order",
+			commands: []string{"normal", "other", "high"},
+			want:     []string{"high", "normal", "other"},
+		},
+		{
+			name:     "subcommands inherit priority",
+			commands: []string{"low", "group"},
+			want:     []string{"group", "low"},
+		},
+	}
+
+	priorities := map[string]int{"low": 1, "high": 10, "normal": 0, "other": 0, "group": 5}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := newTestSubway(t, SubwayOptions{MaxConcurrentInteractions: 1, QueueTimeout: 5 * time.Second})
+
+			for name, priority := range priorities {
+				sub.Commands.MustAddInteractionCommand(&InteractionCommandable{
+					Name:        name,
+					Description: name,
+					Priority:    priority,
+					Handler: func(context.Context, *Subway, discord.Interaction) (*discord.InteractionResponse, error) {
+						return nil, nil
+					},
+				})
+			}
+
+			release, ok := sub.acquireInteractionSlot(context.Background(), newTestCommandInteraction("holder"))
+			if !ok {
+				t.Fatal("acquireInteractionSlot() did not acquire a free slot")
+			}
+
+			var orderMu sync.Mutex
+
+			order := make([]string, 0, len(tt.commands))
+			done := make(chan struct{}, len(tt.commands))
+
+			for i, command := range tt.commands {
+				go func() {
+					release, ok := sub.acquireInteractionSlot(context.Background(), newTestCommandInteraction(command))
+					if !ok {
+						t.Errorf("acquireInteractionSlot(%s) timed out", command)
+					}
+
+					orderMu.Lock()
+					order = append(order, command)
+					orderMu.Unlock()
+
+					release()
+					done <- struct{}{}
+				}()
+
+				// Wait for each waiter to queue, so they arrive in order.
+				waitForQueueLength(t, sub.limiter, i+1)
+			}
+
+			release()
+
+			for range tt.commands {
+				<-done
+			}
+
+			for i := range tt.want {
+				if order[i] != tt.want[i] {
+					t.Fatalf("order = %v, want %v", order, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestAcquireInteractionSlotTimeout(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		cancel  bool
+	}{
+		{name: "queue timeout", timeout: 10 * time.Millisecond},
+		{name: "context cancelled", timeout: time.Second, cancel: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := newTestSubway(t, SubwayOptions{MaxConcurrentInteractions: 1, QueueTimeout: tt.timeout})
+
+			release, ok := sub.acquireInteractionSlot(context.Background(), newTestCommandInteraction("holder"))
+			if !ok {
+				t.Fatal("acquireInteractionSlot() did not acquire a free slot")
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			if _, ok := sub.acquireInteractionSlot(ctx, newTestCommandInteraction("waiter")); ok {
+				t.Fatal("acquireInteractionSlot() acquired a slot whilst the limiter was full")
+			}
+
+			release()
+
+			if sub.limiter.active != 0 || sub.limiter.waiters.Len() != 0 {
+				t.Fatalf("active = %d, waiters = %d, want 0 and 0", sub.limiter.active, sub.limiter.waiters.Len())
+			}
+		})
+	}
+}
+
+func TestAcquireInteractionSlotTimeoutHandoff(t *testing.T) {
+	sub := newTestSubway(t, SubwayOptions{MaxConcurrentInteractions: 1, QueueTimeout: time.Second})
+
+	// A slot may be handed over as the waiter gives up. Either way, the slot must not leak.
+	for range 200 {
+		release, ok := sub.acquireInteractionSlot(context.Background(), newTestCommandInteraction("holder"))
+		if !ok {
+			t.Fatal("acquireInteractionSlot() did not acquire a free slot")
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		result := make(chan func(), 1)
+
+		go func() {
+			waiterRelease, ok := sub.acquireInteractionSlot(ctx, newTestCommandInteraction("waiter"))
+			if !ok {
+				waiterRelease = func() {}
+			}
+
+			result <- waiterRelease
+		}()
+
+		waitForQueueLength(t, sub.limiter, 1)
+
+		go cancel()
+		release()
+
+		(<-result)()
+
+		sub.limiter.mu.Lock()
+		active, queued := sub.limiter.active, sub.limiter.waiters.Len()
+		sub.limiter.mu.Unlock()
+
+		if active != 0 || queued != 0 {
+			t.Fatalf("active = %d, waiters = %d, want 0 and 0", active, queued)
+		}
+	}
+}
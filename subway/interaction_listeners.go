@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/WelcomerTeam/Discord/discord"
@@ -66,3 +70,160 @@ func (sub *Subway) HandleComponent(interaction discord.Interaction, customID str
 
 	return listener
 }
+
+// ModalSubmission is a submitted modal sent to a modal listener channel.
+type ModalSubmission struct {
+	Interaction *discord.Interaction
+
+	// Arguments are the text inputs of the modal, named after their custom ID.
+	Arguments map[string]*Argument
+}
+
+// Value returns the value of a text input, or an empty string if it was not submitted.
+func (submission *ModalSubmission) Value(customID string) string {
+	argument, ok := submission.Arguments[customID]
+	if !ok {
+		return ""
+	}
+
+	value, _ := argument.String()
+
+	return value
+}
+
+type ModalListener struct {
+	Channel            chan *ModalSubmission
+	InitialInteraction discord.Interaction
+	Handler            InteractionHandler
+
+	createdAt time.Time
+	expiresAt time.Time
+
+	// Guards Channel, as submissions may arrive whilst the listener is being cancelled.
+	channelMu sync.Mutex
+
+	// Internal for easier cancellation
+	subway *Subway
+	key    string
+}
+
+// Cancel stops listening for a modal and closes the channel, if one is present.
+func (listener *ModalListener) Cancel() {
+	listener.channelMu.Lock()
+
+	if listener.Channel != nil {
+		close(listener.Channel)
+
+		listener.Channel = nil
+	}
+
+	listener.channelMu.Unlock()
+
+	listener.subway.ModalListenersMu.Lock()
+	if listener.subway.ModalListeners[listener.key] == listener {
+		delete(listener.subway.ModalListeners, listener.key)
+	}
+	listener.subway.ModalListenersMu.Unlock()
+}
+
+// deliver passes a submitted modal to the listener channel. Returns false if the
+// listener has been cancelled or is already holding an unread submission.
+func (listener *ModalListener) deliver(submission *ModalSubmission) bool {
+	listener.channelMu.Lock()
+	defer listener.channelMu.Unlock()
+
+	if listener.Channel == nil {
+		return false
+	}
+
+	select {
+	case listener.Channel <- submission:
+		return true
+	default:
+		return false
+	}
+}
+
+// HandleModal allows you to listen for a specific modal submission. You can either
+// use a callback function which is automatically handled or use a channel.
+// Submissions from any user with the custom ID are passed to the listener.
+func (sub *Subway) HandleModal(interaction discord.Interaction, customID string, timeout time.Duration, handler InteractionHandler) *ModalListener {
+	return sub.addModalListener(interaction, customID, timeout, handler)
+}
+
+// getUserModalListenerKey returns the key of a modal listener that only receives submissions from a user.
+func getUserModalListenerKey(customID string, userID discord.Snowflake) string {
+	return customID + ":" + strconv.FormatInt(int64(userID), 10)
+}
+
+func (sub *Subway) addModalListener(interaction discord.Interaction, key string, timeout time.Duration, handler InteractionHandler) *ModalListener {
+	now := time.Now()
+
+	listener := &ModalListener{
+		Channel:            nil,
+		InitialInteraction: interaction,
+		Handler:            handler,
+		createdAt:          now,
+		expiresAt:          now.Add(timeout),
+		subway:             sub,
+		key:                key,
+	}
+
+	if handler == nil {
+		listener.Channel = make(chan *ModalSubmission, 1)
+	}
+
+	sub.ModalListenersMu.RLock()
+	existing, ok := sub.ModalListeners[key]
+	sub.ModalListenersMu.RUnlock()
+
+	if ok {
+		existing.Cancel()
+	}
+
+	sub.ModalListenersMu.Lock()
+	sub.ModalListeners[key] = listener
+	sub.ModalListenersMu.Unlock()
+
+	return listener
+}
+
+// OpenModal responds to an interaction with a modal and blocks until it has been submitted
+// by the user of the interaction, the timeout has passed or the context is cancelled. The interaction passed must not have
+// been responded to yet, so the handler calling this should return a nil response.
+// The submission is returned with the text inputs of the modal. The submitted interaction
+// must be responded to by the caller, for example with interaction.SendResponse.
+func (sub *Subway) OpenModal(ctx context.Context, interaction discord.Interaction, customID, title string, components []discord.InteractionComponent, timeout time.Duration) (*ModalSubmission, error) {
+	// The listener is keyed by the user, so users opening the same modal at once receive their own submission.
+	listener := sub.addModalListener(interaction, getUserModalListenerKey(customID, getInteractionUserID(interaction)), timeout, nil)
+	channel := listener.Channel
+
+	defer listener.Cancel()
+
+	err := interaction.SendResponse(ctx, sub.getEmptySession(ctx), discord.InteractionCallbackTypeModal, &discord.InteractionCallbackData{
+		CustomID:   customID,
+		Title:      title,
+		Components: components,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to send modal: %w", err)
+	}
+
+	MarkInteractionAcknowledged(ctx)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case submission, ok := <-channel:
+		if !ok {
+			return nil, ErrModalListenerNotFound
+		}
+
+		return submission, nil
+	case <-timer.C:
+		return nil, ErrModalTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
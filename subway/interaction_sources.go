@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	discord "github.com/WelcomerTeam/Discord/discord"
+	protobuf "github.com/WelcomerTeam/Sandwich-Daemon/protobuf"
+	sandwich_structs "github.com/WelcomerTeam/Sandwich-Daemon/structs"
+	"github.com/rs/zerolog"
+)
+
+const sandwichInteractionCreateEvent = "INTERACTION_CREATE"
+
+// InteractionSource provides interactions that are received outside of the HTTP endpoint,
+// such as INTERACTION_CREATE events from the gateway.
+type InteractionSource interface {
+	// Listen sends the raw JSON of every interaction received to the channel. This should
+	// block until the context is cancelled or the source is no longer able to receive.
+	Listen(ctx context.Context, interactions chan<- []byte) error
+}
+
+// ListenToInteractionSource processes interactions from an InteractionSource. Responses are sent
+// through the interaction callback endpoint. This blocks until the source stops listening.
+func (sub *Subway) ListenToInteractionSource(ctx context.Context, source InteractionSource) error {
+	sub.Start()
+
+	interactions := make(chan []byte)
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- source.Listen(ctx, interactions)
+	}()
+
+	for {
+		select {
+		case rawInteraction := <-interactions:
+			go func() {
+				err := sub.HandleInteractionEvent(rawInteraction)
+				if err != nil {
+					sub.Logger.Warn().Err(err).Msg("Failed to handle interaction event")
+				}
+			}()
+		case err := <-errs:
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return fmt.Errorf("failed to listen to interaction source: %w", err)
+			}
+
+			return nil
+		}
+	}
+}
+
+// HandleInteractionEvent processes the raw JSON of an interaction that was not received
+// through the HTTP endpoint. The response is sent through the interaction callback endpoint.
+func (sub *Subway) HandleInteractionEvent(rawInteraction []byte) error {
+	sub.Start()
+
+	start := time.Now()
+
+	var interaction discord.Interaction
+
+	err := json.Unmarshal(rawInteraction, &interaction)
+	if err != nil {
+		return fmt.Errorf("failed to parse interaction: %w", err)
+	}
+
+	if interaction.Type == discord.InteractionTypePing {
+		return nil
+	}
+
+	if !sub.beginInteraction() {
+		return ErrSubwayShuttingDown
+	}
+
+	ctx := sub.newInteractionContext(interaction, rawInteraction)
+
+	sub.dispatchInteraction(ctx, interaction, start, func(response *discord.InteractionResponse, err error) {
+		// The response may be from an error handler, so it is sent even if an error occurred.
+		if response == nil {
+			return
+		}
+
+		// CreateInteractionResponse expects the response to always have data.
+		if response.Data == nil {
+			response = &discord.InteractionResponse{
+				Type: response.Type,
+				Data: &discord.InteractionCallbackData{},
+			}
+		}
+
+		err = discord.CreateInteractionResponse(ctx, sub.getEmptySession(ctx), interaction.ID, interaction.Token, *response)
+		if err != nil {
+			sub.getLogger(ctx).Error().Err(err).Msg("Failed to send interaction response")
+		}
+	})
+
+	return nil
+}
+
+// ChannelInteractionSource is an in-memory InteractionSource, useful for tests.
+type ChannelInteractionSource struct {
+	interactions chan []byte
+}
+
+// NewChannelInteractionSource creates a new in-memory InteractionSource.
+func NewChannelInteractionSource(buffer int) *ChannelInteractionSource {
+	return &ChannelInteractionSource{
+		interactions: make(chan []byte, buffer),
+	}
+}
+
+// Send queues an interaction to be processed.
+func (source *ChannelInteractionSource) Send(interaction discord.Interaction) error {
+	rawInteraction, err := json.Marshal(interaction)
+	if err != nil {
+		return fmt.Errorf("failed to marshal interaction: %w", err)
+	}
+
+	source.interactions <- rawInteraction
+
+	return nil
+}
+
+// SendRaw queues the raw JSON of an interaction to be processed.
+func (source *ChannelInteractionSource) SendRaw(rawInteraction []byte) {
+	source.interactions <- rawInteraction
+}
+
+func (source *ChannelInteractionSource) Listen(ctx context.Context, interactions chan<- []byte) error {
+	for {
+		select {
+		case rawInteraction := <-source.interactions:
+			select {
+			case interactions <- rawInteraction:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Backoff between reconnecting to the sandwich gRPC stream, which doubles after each failure.
+const (
+	minimumGRPCBackoff = time.Second
+	maximumGRPCBackoff = 30 * time.Second
+)
+
+// SandwichInteractionSource receives INTERACTION_CREATE events from Sandwich. Events are read from
+// the Sandwich gRPC stream and, if provided, from raw sandwich payloads such as a NATS consumer.
+type SandwichInteractionSource struct {
+	SandwichClient protobuf.SandwichClient
+	Identifier     string
+	Logger         zerolog.Logger
+
+	// Payloads receives the raw JSON of sandwich payloads, if events are consumed outside of gRPC.
+	Payloads <-chan []byte
+}
+
+func (source *SandwichInteractionSource) Listen(ctx context.Context, interactions chan<- []byte) error {
+	payloads := make(chan []byte)
+
+	if source.SandwichClient != nil {
+		go source.listenToGRPC(ctx, payloads)
+	}
+
+	for {
+		var data []byte
+
+		select {
+		case data = <-payloads:
+		case data = <-source.Payloads:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		var payload sandwich_structs.SandwichPayload
+
+		err := json.Unmarshal(data, &payload)
+		if err != nil || payload.Type != sandwichInteractionCreateEvent {
+			continue
+		}
+
+		select {
+		case interactions <- payload.Data:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (source *SandwichInteractionSource) listenToGRPC(ctx context.Context, payloads chan<- []byte) {
+	backoff := minimumGRPCBackoff
+
+	for ctx.Err() == nil {
+		listener, err := source.SandwichClient.Listen(ctx, &protobuf.ListenRequest{
+			Identifier: source.Identifier,
+		})
+		if err != nil {
+			source.Logger.Warn().Err(err).Dur("backoff", backoff).Msg("Failed to listen to grpc")
+
+			backoff = waitForGRPCBackoff(ctx, backoff)
+
+			continue
+		}
+
+		for {
+			var listenResponse protobuf.ListenResponse
+
+			err = listener.RecvMsg(&listenResponse)
+			if err != nil {
+				if ctx.Err() == nil {
+					source.Logger.Warn().Err(err).Dur("backoff", backoff).Msg("Failed to receive grpc message")
+				}
+
+				break
+			}
+
+			// The stream is connected, so the next reconnect starts with the minimum backoff.
+			backoff = minimumGRPCBackoff
+
+			select {
+			case payloads <- listenResponse.Data:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Streams are created lazily, so reconnecting to sandwich whilst it is down would not block.
+		backoff = waitForGRPCBackoff(ctx, backoff)
+	}
+}
+
+// waitForGRPCBackoff waits for the backoff, or until the context is done, and returns the next backoff.
+func waitForGRPCBackoff(ctx context.Context, backoff time.Duration) time.Duration {
+	select {
+	case <-time.After(backoff):
+	case <-ctx.Done():
+	}
+
+	return min(backoff*2, maximumGRPCBackoff)
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"fmt"
+	"math"
 	"strings"
 
 	"github.com/WelcomerTeam/Discord/discord"
@@ -43,6 +44,11 @@ type InteractionCommandable struct {
 	Checks            []InteractionCheckFuncType
 	ArgumentParameter []ArgumentParameter
 
+	// ArgumentStruct is a struct, or a pointer to one, whose fields declare the arguments of
+	// the command. ArgumentParameter is derived from it when the command is added and a filled
+	// copy is available to the handler using GetArgumentStruct.
+	ArgumentStruct interface{}
+
 	Handler      InteractionHandler
 	ErrorHandler InteractionErrorHandler
 
@@ -51,37 +57,56 @@ type InteractionCommandable struct {
 
 	AutocompleteHandler InteractionAutocompleteHandler
 
+	// Priority of the command when interactions are queued. Higher priorities are processed
+	// first. Commands without a priority inherit the priority of their parent.
+	Priority int
+
+	// EphemeralDefer makes the response sent when the handler is deferred ephemeral, so
+	// ephemeral responses stay ephemeral if the handler takes too long. Subcommands of a
+	// command with EphemeralDefer are also deferred ephemerally.
+	EphemeralDefer bool
+
+	// Scope the command is registered in when syncing. Commands without a scope
+	// inherit the scope of their cog and are otherwise registered globally.
+	Scope *CommandScope
+
 	commands map[string]*InteractionCommandable
 	parent   *InteractionCommandable
+
+	argumentStruct *argumentStruct
+
+	// converters are used to map arguments to options. Only set on the root of a command tree.
+	converters *InteractionConverters
 }
 
 func (ic *InteractionCommandable) MapApplicationCommands() []discord.ApplicationCommand {
 	applicationCommands := make([]discord.ApplicationCommand, 0, len(ic.commands))
 
-	applicationCommandType := discord.ApplicationCommandTypeChatInput
-
-	var applicationType *discord.ApplicationCommandType
+	// Commands are sorted so the output does not depend on map iteration.
+	for _, interactionCommandable := range ic.getSortedCommands() {
+		applicationCommands = append(applicationCommands, interactionCommandable.mapApplicationCommand())
+	}
 
-	for _, interactionCommandable := range ic.commands {
-		if interactionCommandable.CommandType != nil {
-			applicationType = interactionCommandable.CommandType
-		} else {
-			applicationType = &applicationCommandType
-		}
+	return applicationCommands
+}
 
-		applicationCommands = append(applicationCommands, discord.ApplicationCommand{
-			Name:                     interactionCommandable.Name,
-			NameLocalizations:        interactionCommandable.NameLocalizations,
-			Description:              interactionCommandable.Description,
-			DescriptionLocalizations: interactionCommandable.DescriptionLocalizations,
-			Options:                  interactionCommandable.MapApplicationOptions(),
-			DefaultMemberPermission:  interactionCommandable.DefaultMemberPermission,
-			DMPermission:             interactionCommandable.DMPermission,
-			Type:                     applicationType,
-		})
+func (ic *InteractionCommandable) mapApplicationCommand() discord.ApplicationCommand {
+	applicationType := ic.CommandType
+	if applicationType == nil {
+		applicationCommandType := discord.ApplicationCommandTypeChatInput
+		applicationType = &applicationCommandType
 	}
 
-	return applicationCommands
+	return discord.ApplicationCommand{
+		Name:                     ic.Name,
+		NameLocalizations:        ic.NameLocalizations,
+		Description:              ic.Description,
+		DescriptionLocalizations: ic.DescriptionLocalizations,
+		Options:                  ic.MapApplicationOptions(),
+		DefaultMemberPermission:  ic.DefaultMemberPermission,
+		DMPermission:             ic.DMPermission,
+		Type:                     applicationType,
+	}
 }
 
 func (ic *InteractionCommandable) MapApplicationOptions() (applicationOptions []discord.ApplicationCommandOption) {
@@ -90,7 +115,7 @@ func (ic *InteractionCommandable) MapApplicationOptions() (applicationOptions []
 	var applicationOptionType discord.ApplicationCommandOptionType
 
 	// Map subgroups/subcommands.
-	for _, command := range ic.commands {
+	for _, command := range ic.getSortedCommands() {
 		switch command.Type {
 		case InteractionCommandableTypeCommand:
 			applicationOptionType = discord.ApplicationCommandOptionTypeSubCommand
@@ -110,60 +135,31 @@ func (ic *InteractionCommandable) MapApplicationOptions() (applicationOptions []
 		})
 	}
 
-	var channelType discord.ChannelType
-
-	var hasChannel bool
+	converters := ic.getConverters()
 
 	// Map arguments.
 	for _, argument := range ic.ArgumentParameter {
-		channelType = 0
-		hasChannel = false
-
-		switch argument.ArgumentType {
-		case ArgumentTypeSnowflake:
-			applicationOptionType = discord.ApplicationCommandOptionTypeString
-		case ArgumentTypeMember, ArgumentTypeUser:
-			applicationOptionType = discord.ApplicationCommandOptionTypeUser
-		case ArgumentTypeTextChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildText
-			hasChannel = true
-		case ArgumentTypeVoiceChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildVoice
-			hasChannel = true
-		case ArgumentTypeStageChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildStageVoice
-			hasChannel = true
-		case ArgumentTypeCategoryChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildCategory
-			hasChannel = true
-		case ArgumentTypeStoreChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildStore
-			hasChannel = true
-		case ArgumentTypeThread:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-			channelType = discord.ChannelTypeGuildPublicThread
-			hasChannel = true
-		case ArgumentTypeGuildChannel:
-			applicationOptionType = discord.ApplicationCommandOptionTypeChannel
-		case ArgumentTypeGuild:
-			applicationOptionType = discord.ApplicationCommandOptionTypeString
-		case ArgumentTypeRole:
-			applicationOptionType = discord.ApplicationCommandOptionTypeRole
-		case ArgumentTypeColour, ArgumentTypeEmoji, ArgumentTypePartialEmoji, ArgumentTypeString:
-			applicationOptionType = discord.ApplicationCommandOptionTypeString
-		case ArgumentTypeBool:
-			applicationOptionType = discord.ApplicationCommandOptionTypeBoolean
-		case ArgumentTypeFloat:
-			applicationOptionType = discord.ApplicationCommandOptionTypeString
-		case ArgumentTypeInt:
-			applicationOptionType = discord.ApplicationCommandOptionTypeInteger
+		applicationOptionType = discord.ApplicationCommandOptionTypeString
+		channelTypes := argument.ChannelTypes
+		autocomplete := argument.Autocomplete
+
+		// Arguments without a converter are registered as strings, so the
+		// command is still able to be registered.
+		if converter := converters.GetConverter(argument.ArgumentType); converter != nil {
+			applicationOptionType = converter.optionType
+
+			if len(channelTypes) == 0 {
+				channelTypes = converter.channelTypes
+			}
+
+			if autocomplete == nil && converter.autocomplete != nil && len(argument.Choices) == 0 {
+				hasAutocomplete := true
+				autocomplete = &hasAutocomplete
+			}
 		}
 
+		minValue, maxValue := getApplicationOptionBounds(argument)
+
 		commandOption := discord.ApplicationCommandOption{
 			Type:                     applicationOptionType,
 			Name:                     argument.Name,
@@ -172,16 +168,12 @@ func (ic *InteractionCommandable) MapApplicationOptions() (applicationOptions []
 			DescriptionLocalizations: argument.DescriptionLocalizations,
 			Required:                 argument.Required,
 			Choices:                  argument.Choices,
-			ChannelTypes:             argument.ChannelTypes,
-			MinValue:                 argument.MinValue,
-			MaxValue:                 argument.MaxValue,
+			ChannelTypes:             channelTypes,
+			MinValue:                 minValue,
+			MaxValue:                 maxValue,
 			MinLength:                argument.MinLength,
 			MaxLength:                argument.MaxLength,
-			Autocomplete:             argument.Autocomplete,
-		}
-
-		if hasChannel {
-			commandOption.ChannelTypes = []discord.ChannelType{channelType}
+			Autocomplete:             autocomplete,
 		}
 
 		applicationOptions = append(applicationOptions, commandOption)
@@ -190,6 +182,56 @@ func (ic *InteractionCommandable) MapApplicationOptions() (applicationOptions []
 	return applicationOptions
 }
 
+// getConverters returns the converters of the command tree. Commands that have
+// not been added to a command tree use the default converters.
+func (ic *InteractionCommandable) getConverters() *InteractionConverters {
+	converters := ic.getRootConverters()
+	if converters == nil {
+		return defaultInteractionConverters
+	}
+
+	return converters
+}
+
+// getRootConverters returns the converters of the command tree, or nil if the
+// command has not been added to a command tree.
+func (ic *InteractionCommandable) getRootConverters() *InteractionConverters {
+	root := ic
+
+	for root.parent != nil {
+		root = root.parent
+	}
+
+	return root.converters
+}
+
+// getApplicationOptionBounds returns the bounds of an argument sent to discord. Fractional bounds
+// are rounded outwards, as options only have whole bounds, and are enforced when preparing.
+func getApplicationOptionBounds(argument ArgumentParameter) (minValue, maxValue *int32) {
+	minValue, maxValue = argument.MinValue, argument.MaxValue
+
+	// Bounds that do not fit are left unset.
+	if argument.MinNumber != nil {
+		minValue = nil
+
+		if *argument.MinNumber >= math.MinInt32 && *argument.MinNumber <= math.MaxInt32 {
+			bound := int32(math.Floor(*argument.MinNumber))
+			minValue = &bound
+		}
+	}
+
+	if argument.MaxNumber != nil {
+		maxValue = nil
+
+		if *argument.MaxNumber >= math.MinInt32 && *argument.MaxNumber <= math.MaxInt32 {
+			bound := int32(math.Ceil(*argument.MaxNumber))
+			maxValue = &bound
+		}
+	}
+
+	return minValue, maxValue
+}
+
 func (ic *InteractionCommandable) MustAddInteractionCommand(interactionCommandable *InteractionCommandable) (icc *InteractionCommandable) {
 	icc, err := ic.AddInteractionCommand(interactionCommandable)
 	if err != nil {
@@ -200,6 +242,26 @@ func (ic *InteractionCommandable) MustAddInteractionCommand(interactionCommandab
 }
 
 func (ic *InteractionCommandable) AddInteractionCommand(interactionCommandable *InteractionCommandable) (icc *InteractionCommandable, err error) {
+	path := strings.TrimSpace(ic.getCommandPath() + " " + interactionCommandable.Name)
+
+	err = interactionCommandable.setupArgumentStruct(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Commands added to a command tree without converters are checked once the tree is added to one.
+	if converters := ic.getRootConverters(); converters != nil {
+		err = interactionCommandable.checkArgumentStructConverters(path, converters)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = ic.validateChild(interactionCommandable)
+	if err != nil {
+		return nil, err
+	}
+
 	// If this command is not a base command, turn it into a subcommand
 	if ic.Type == InteractionCommandableTypeCommand && ic.parent != nil {
 		ic.Type = InteractionCommandableTypeSubcommand
@@ -309,7 +371,7 @@ func (ic *InteractionCommandable) IsGroup() bool {
 }
 
 // Invoke handles the execution of a command or a group.
-func (ic *InteractionCommandable) Invoke(ctx context.Context, sub *Subway, interaction discord.Interaction) (*discord.InteractionResponse, error) {
+func (ic *InteractionCommandable) Invoke(ctx context.Context, sub *Subway, interaction discord.Interaction) (resp *discord.InteractionResponse, err error) {
 	commandBranch := GetCommandBranchFromContext(ctx)
 
 	if len(commandBranch) > 0 {
@@ -326,27 +388,23 @@ func (ic *InteractionCommandable) Invoke(ctx context.Context, sub *Subway, inter
 			return commandable.Invoke(commandContext, sub, interaction)
 		}
 
-		sub.Logger.Warn().
+		sub.getLogger(ctx).Warn().
 			Str("command", ic.Name).
 			Str("branch", commandBranch[0]).
 			Msg("Encountered non-group whilst traversing command tree.")
 	}
 
-	ctx, err := ic.prepare(ctx, sub, interaction)
-	if err != nil {
-		return nil, err
-	}
-
 	defer func() {
 		errorValue := recover()
 		if errorValue != nil {
-			sub.Logger.Error().Interface("errorValue", errorValue).Msg("Recovered panic on event dispatch")
-
-			ic.propagateError(ctx, sub, interaction, PanicError{errorValue})
+			resp, err = sub.handlePanic(ctx, ic, interaction, errorValue)
 		}
 	}()
 
-	var resp *discord.InteractionResponse
+	ctx, err = ic.prepare(ctx, sub, interaction)
+	if err != nil {
+		return nil, err
+	}
 
 	switch interaction.Type {
 	case discord.InteractionTypeApplicationCommand,
@@ -361,8 +419,8 @@ func (ic *InteractionCommandable) Invoke(ctx context.Context, sub *Subway, inter
 			return ic.propagateError(ctx, sub, interaction, ErrCommandNotFound), ErrCommandNotFound
 		}
 	case discord.InteractionTypeApplicationCommandAutocomplete:
-		if ic.AutocompleteHandler != nil {
-			choices, err := ic.AutocompleteHandler(ctx, sub, interaction)
+		if autocompleteHandler := ic.getAutocompleteHandler(ctx, sub); autocompleteHandler != nil {
+			choices, err := autocompleteHandler(ctx, sub, interaction)
 			if err != nil {
 				return ic.propagateError(ctx, sub, interaction, err), err
 			}
@@ -381,6 +439,28 @@ func (ic *InteractionCommandable) Invoke(ctx context.Context, sub *Subway, inter
 	return resp, nil
 }
 
+// getAutocompleteHandler returns the autocomplete handler of the command. If the command does not
+// have one, the autocomplete of the converter of the focused argument is used.
+func (ic *InteractionCommandable) getAutocompleteHandler(ctx context.Context, sub *Subway) InteractionAutocompleteHandler {
+	if ic.AutocompleteHandler != nil {
+		return ic.AutocompleteHandler
+	}
+
+	rawOptions := GetRawOptionsFromContext(ctx)
+
+	for _, argumentParameter := range ic.ArgumentParameter {
+		if !rawOptions[argumentParameter.Name].Focused {
+			continue
+		}
+
+		if converter := sub.Converters.GetConverter(argumentParameter.ArgumentType); converter != nil {
+			return converter.autocomplete
+		}
+	}
+
+	return nil
+}
+
 // propagateError propagates an error to the current command or parent. It will execute the root parent first,
 // then go up from there. It will return the highest up error handler in the chain that returns a interaction response.
 // If the command and root error handler returns an interaction response, the command error handler response will be
@@ -412,7 +492,7 @@ func (ic *InteractionCommandable) propagateError(ctx context.Context, sub *Subwa
 
 // Default error propagator. This will just log an exception.
 func defaultErrorPropagator(ctx context.Context, sub *Subway, interaction discord.Interaction, err error) (*discord.InteractionResponse, error) {
-	sub.Logger.Error().Err(err).Msg("Exception executing interaction")
+	sub.getLogger(ctx).Error().Err(err).Msg("Exception executing interaction")
 
 	return nil, err
 }
@@ -455,22 +535,57 @@ func (ic *InteractionCommandable) prepare(ctx context.Context, sub *Subway, inte
 // parseArguments generates the arguments for a command.
 func (ic *InteractionCommandable) parseArguments(ctx context.Context, sub *Subway, interaction discord.Interaction) (context.Context, error) {
 	arguments := map[string]*Argument{}
+	rawOptions := GetRawOptionsFromContext(ctx)
 
 	for _, argumentParameter := range ic.ArgumentParameter {
 		transformed, err := ic.transform(ctx, sub, interaction, argumentParameter)
-		if err != nil {
+
+		switch {
+		case err == nil:
+		case rawOptions[argumentParameter.Name].Focused:
+			// Focused arguments of autocomplete interactions are partial, so may not convert.
+			transformed = nil
+		default:
 			return ctx, err
 		}
 
+		provided := transformed != nil
+
+		// Focused arguments of autocomplete interactions are partial, so are not validated.
+		if provided && !rawOptions[argumentParameter.Name].Focused {
+			err = validateArgument(argumentParameter, rawOptions[argumentParameter.Name], transformed)
+			if err != nil {
+				return ctx, err
+			}
+		}
+
+		if !provided {
+			transformed = sub.getArgumentDefault(argumentParameter)
+		}
+
 		arguments[argumentParameter.Name] = &Argument{
 			ArgumentType: argumentParameter.ArgumentType,
 			value:        transformed,
+			provided:     provided,
 		}
 	}
 
 	ctx = AddArgumentsToContext(ctx, arguments)
 
-	return ctx, nil
+	return ic.fillArgumentStruct(ctx, arguments)
+}
+
+// getArgumentDefault returns the value of an argument that was not provided.
+func (sub *Subway) getArgumentDefault(argumentParameter ArgumentParameter) interface{} {
+	if argumentParameter.Default != nil {
+		return argumentParameter.Default
+	}
+
+	if converter := sub.Converters.GetConverter(argumentParameter.ArgumentType); converter != nil {
+		return converter.data
+	}
+
+	return nil
 }
 
 // transform returns a output value based on the argument parameter passed in.
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -38,16 +39,62 @@ var (
 			Help: "Total failed interactions received",
 		},
 	)
+
+	subwayDuplicateInteractionTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "subway_duplicate_interaction_total",
+			Help: "Total duplicate interaction deliveries received",
+		},
+	)
+
+	subwayInteractionQueueDepth = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "subway_interaction_queue_depth",
+			Help: "Interactions waiting to be processed",
+		},
+	)
+
+	subwayInteractionRejectedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "subway_interaction_rejected_total",
+			Help: "Total interactions rejected for exceeding the queue timeout",
+		},
+	)
+
+	subwaySignatureRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "subway_signature_rejected_total",
+			Help: "Total requests rejected during signature validation",
+		},
+		[]string{"reason"},
+	)
+
+	subwayCommandDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "subway_command_drift",
+			Help: "Set to 1 if the commands registered with discord differ from the command tree",
+		},
+		[]string{"application_id", "guild_id"},
+	)
 )
 
 // SetupPrometheus sets up prometheus.
 func (sub *Subway) SetupPrometheus() error {
-	sub.Logger.Info().Msgf("Serving prometheus at %s", sub.prometheusAddress)
+	return sub.servePrometheus(sub.newPrometheusServer())
+}
 
+// newPrometheusServer registers the subway metrics and creates the prometheus server, so it
+// is able to be shutdown before it has started serving.
+func (sub *Subway) newPrometheusServer() *http.Server {
 	prometheus.MustRegister(subwayInteractionProcessingTimeName)
 	prometheus.MustRegister(subwayInteractionTotal)
 	prometheus.MustRegister(subwaySuccessfulInteractionTotal)
 	prometheus.MustRegister(subwayFailedInteractionTotal)
+	prometheus.MustRegister(subwayDuplicateInteractionTotal)
+	prometheus.MustRegister(subwayInteractionQueueDepth)
+	prometheus.MustRegister(subwayInteractionRejectedTotal)
+	prometheus.MustRegister(subwaySignatureRejectedTotal)
+	prometheus.MustRegister(subwayCommandDrift)
 
 	prometheusMux := http.NewServeMux()
 	prometheusMux.Handle("/metrics", promhttp.HandlerFor(
@@ -55,8 +102,23 @@ func (sub *Subway) SetupPrometheus() error {
 		promhttp.HandlerOpts{},
 	))
 
-	err := http.ListenAndServe(sub.prometheusAddress, prometheusMux)
-	if err != nil {
+	server := &http.Server{
+		Addr:    sub.prometheusAddress,
+		Handler: prometheusMux,
+	}
+
+	sub.serversMu.Lock()
+	sub.prometheusServer = server
+	sub.serversMu.Unlock()
+
+	return server
+}
+
+func (sub *Subway) servePrometheus(server *http.Server) error {
+	sub.Logger.Info().Msgf("Serving prometheus at %s", sub.prometheusAddress)
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		sub.Logger.Error().Str("host", sub.prometheusAddress).Err(err).Msg("Failed to serve prometheus server")
 
 		return fmt.Errorf("failed to serve prometheus: %w", err)
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/WelcomerTeam/Discord/discord"
+)
+
+// AdminCog is a cog listed by the admin API.
+type AdminCog struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Application discord.Snowflake `json:"application_id,omitempty"`
+}
+
+// AdminComponentListener is a component listener listed by the admin API.
+type AdminComponentListener struct {
+	CustomID      string            `json:"custom_id"`
+	InteractionID discord.Snowflake `json:"interaction_id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	ExpiresAt     time.Time         `json:"expires_at"`
+	Channel       bool              `json:"channel"`
+}
+
+// AdminCommands is the command tree listed by the admin API.
+type AdminCommands struct {
+	Commands     []discord.ApplicationCommand                       `json:"commands"`
+	Applications map[discord.Snowflake][]discord.ApplicationCommand `json:"applications"`
+}
+
+// AdminSyncRequest is the body of a sync request to the admin API. If an application ID of a
+// registered application is provided, only that application is synced. If a token is provided,
+// the subway commands are synced to the application ID. Otherwise, all applications are synced.
+type AdminSyncRequest struct {
+	ApplicationID discord.Snowflake `json:"application_id"`
+	Token         string            `json:"token"`
+}
+
+// SetupAdmin serves the admin API at the admin address. This is called by ListenAndServe
+// if an admin token and admin address have been provided.
+func (sub *Subway) SetupAdmin() error {
+	return sub.serveAdmin(sub.newAdminServer())
+}
+
+// newAdminServer creates the admin server, so it is able to be shutdown before it has started serving.
+func (sub *Subway) newAdminServer() *http.Server {
+	server := &http.Server{
+		Addr:    sub.adminAddress,
+		Handler: sub.AdminHandler(),
+	}
+
+	sub.serversMu.Lock()
+	sub.adminServer = server
+	sub.serversMu.Unlock()
+
+	return server
+}
+
+func (sub *Subway) serveAdmin(server *http.Server) error {
+	sub.Logger.Info().Msgf("Serving admin API at %s", sub.adminAddress)
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		sub.Logger.Error().Str("host", sub.adminAddress).Err(err).Msg("Failed to serve admin server")
+
+		return fmt.Errorf("failed to serve admin: %w", err)
+	}
+
+	return nil
+}
+
+// AdminHandler returns the handler for the admin API. All requests must provide the
+// admin token as a bearer token. The admin API is not served on the interaction listener,
+// so this should only be mounted on a private listener. The following routes are served:
+//
+//	GET  /admin/cogs                 lists registered cogs
+//	GET  /admin/commands             lists the command tree as it is synced to discord
+//	GET  /admin/component-listeners  lists active component listeners and their expiry
+//	POST /admin/sync                 syncs commands with discord
+func (sub *Subway) AdminHandler() http.Handler {
+	adminMux := http.NewServeMux()
+	adminMux.HandleFunc("GET /admin/cogs", sub.handleAdminCogs)
+	adminMux.HandleFunc("GET /admin/commands", sub.handleAdminCommands)
+	adminMux.HandleFunc("GET /admin/component-listeners", sub.handleAdminComponentListeners)
+	adminMux.HandleFunc("POST /admin/sync", sub.handleAdminSync)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !sub.verifyAdminToken(r) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+
+			return
+		}
+
+		adminMux.ServeHTTP(w, r)
+	})
+}
+
+func (sub *Subway) verifyAdminToken(r *http.Request) bool {
+	if sub.adminToken == "" {
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(sub.adminToken)) == 1
+}
+
+func (sub *Subway) handleAdminCogs(w http.ResponseWriter, _ *http.Request) {
+	cogs := make([]AdminCog, 0, len(sub.Cogs))
+
+	for _, cog := range sub.Cogs {
+		cogInfo := cog.CogInfo()
+
+		cogs = append(cogs, AdminCog{Name: cogInfo.Name, Description: cogInfo.Description})
+	}
+
+	for _, application := range sub.GetApplications() {
+		for _, cog := range application.Cogs {
+			cogInfo := cog.CogInfo()
+
+			cogs = append(cogs, AdminCog{Name: cogInfo.Name, Description: cogInfo.Description, Application: application.ID})
+		}
+	}
+
+	writeJSONResponse(w, http.StatusOK, cogs)
+}
+
+func (sub *Subway) handleAdminCommands(w http.ResponseWriter, _ *http.Request) {
+	commands := AdminCommands{
+		Commands:     sub.Commands.MapApplicationCommands(),
+		Applications: make(map[discord.Snowflake][]discord.ApplicationCommand),
+	}
+
+	for _, application := range sub.GetApplications() {
+		commands.Applications[application.ID] = application.Commands.MapApplicationCommands()
+	}
+
+	writeJSONResponse(w, http.StatusOK, commands)
+}
+
+func (sub *Subway) handleAdminComponentListeners(w http.ResponseWriter, _ *http.Request) {
+	sub.ComponentListenersMu.RLock()
+
+	listeners := make([]AdminComponentListener, 0, len(sub.ComponentListeners))
+
+	for customID, listener := range sub.ComponentListeners {
+		listeners = append(listeners, AdminComponentListener{
+			CustomID:      customID,
+			InteractionID: listener.InitialInteraction.ID,
+			CreatedAt:     listener.createdAt,
+			ExpiresAt:     listener.expiresAt,
+			Channel:       listener.Channel != nil,
+		})
+	}
+
+	sub.ComponentListenersMu.RUnlock()
+
+	writeJSONResponse(w, http.StatusOK, listeners)
+}
+
+func (sub *Subway) handleAdminSync(w http.ResponseWriter, r *http.Request) {
+	var syncRequest AdminSyncRequest
+
+	err := json.NewDecoder(r.Body).Decode(&syncRequest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+
+		return
+	}
+
+	application := sub.GetApplication(syncRequest.ApplicationID)
+
+	switch {
+	case application != nil:
+		err = application.SyncCommands(r.Context())
+	case syncRequest.Token != "":
+		if syncRequest.ApplicationID.IsNil() {
+			err = ErrMissingApplicationID
+
+			break
+		}
+
+		err = sub.SyncCommands(r.Context(), syncRequest.Token, syncRequest.ApplicationID)
+	case syncRequest.ApplicationID.IsNil():
+		err = sub.SyncAllCommands(r.Context())
+	default:
+		err = ErrMissingApplicationToken
+	}
+
+	if err != nil {
+		sub.Logger.Error().Err(err).Int64("application_id", int64(syncRequest.ApplicationID)).Msg("Failed to sync commands from admin API")
+
+		writeJSONResponse(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+
+		return
+	}
+
+	sub.Logger.Info().Int64("application_id", int64(syncRequest.ApplicationID)).Msg("Synced commands from admin API")
+
+	writeJSONResponse(w, http.StatusOK, map[string]bool{"synced": true})
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// ReadinessReport is the response of the readiness endpoint.
+type ReadinessReport struct {
+	Ready        bool     `json:"ready"`
+	ShuttingDown bool     `json:"shutting_down"`
+	Sandwich     string   `json:"sandwich"`
+	Commands     int      `json:"commands"`
+	Cogs         []string `json:"cogs"`
+}
+
+// HandleHealthRequest reports that subway is running. This does not check any dependencies.
+func (sub *Subway) HandleHealthRequest(w http.ResponseWriter, _ *http.Request) {
+	writeJSONResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// HandleReadyRequest reports if subway is able to process interactions. Subway is ready
+// once commands have loaded and, if SandwichConnection is provided, sandwich is reachable.
+func (sub *Subway) HandleReadyRequest(w http.ResponseWriter, _ *http.Request) {
+	report := sub.GetReadiness()
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+	}
+
+	writeJSONResponse(w, status, report)
+}
+
+// GetReadiness returns the current readiness of subway.
+func (sub *Subway) GetReadiness() ReadinessReport {
+	sub.inFlightMu.RLock()
+	shuttingDown := sub.shuttingDown
+	sub.inFlightMu.RUnlock()
+
+	report := ReadinessReport{
+		ShuttingDown: shuttingDown,
+		Sandwich:     "unknown",
+		Commands:     len(sub.Commands.GetAllCommands()),
+		Cogs:         getCogNames(sub.Cogs),
+	}
+
+	for _, application := range sub.GetApplications() {
+		report.Commands += len(application.Commands.GetAllCommands())
+		report.Cogs = append(report.Cogs, getCogNames(application.Cogs)...)
+	}
+
+	sandwichReady := true
+
+	if sub.SandwichConnection != nil {
+		state := sub.SandwichConnection.GetState()
+		report.Sandwich = state.String()
+
+		switch state {
+		case connectivity.Ready:
+		case connectivity.Idle:
+			// The connection is lazy, so this starts connecting without waiting for a request.
+			sub.SandwichConnection.Connect()
+		default:
+			sandwichReady = false
+		}
+	}
+
+	report.Ready = !shuttingDown && sandwichReady && report.Commands > 0
+
+	return report
+}
+
+func getCogNames(cogs map[string]Cog) []string {
+	names := make([]string, 0, len(cogs))
+
+	for name := range cogs {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func writeJSONResponse(w http.ResponseWriter, status int, value interface{}) {
+	resp, err := json.Marshal(value)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_, _ = w.Write(resp)
+}
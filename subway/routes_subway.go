@@ -5,15 +5,74 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/WelcomerTeam/Discord/discord"
 	sandwich "github.com/WelcomerTeam/Sandwich/sandwich"
+	"github.com/rs/zerolog"
 )
 
 var InteractionPongResponse = []byte(`{"type":1}`)
 
+// Middleware wraps a http.Handler, allowing for requests to be inspected or
+// rejected before they reach subway.
+type Middleware func(next http.Handler) http.Handler
+
+// ServeHTTP allows for subway to be used as a http.Handler. Requests are passed through
+// all middlewares before being handled by HandleSubwayRequest. The middleware chain is
+// built on the first request and rebuilt when Use is called.
+func (sub *Subway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sub.getHandler().ServeHTTP(w, r)
+}
+
+// Use appends middlewares to the middleware chain. This should be called before serving requests.
+func (sub *Subway) Use(middlewares ...Middleware) {
+	sub.handlerMu.Lock()
+	defer sub.handlerMu.Unlock()
+
+	sub.Middlewares = append(sub.Middlewares, middlewares...)
+	sub.handler = nil
+}
+
+// getHandler returns the middleware chain, building it if it has not been built yet.
+func (sub *Subway) getHandler() http.Handler {
+	sub.handlerMu.RLock()
+	handler := sub.handler
+	sub.handlerMu.RUnlock()
+
+	if handler != nil {
+		return handler
+	}
+
+	sub.handlerMu.Lock()
+	defer sub.handlerMu.Unlock()
+
+	if sub.handler != nil {
+		return sub.handler
+	}
+
+	handler = http.HandlerFunc(sub.HandleSubwayRequest)
+
+	for i := len(sub.Middlewares) - 1; i >= 0; i-- {
+		handler = sub.Middlewares[i](handler)
+	}
+
+	sub.handler = handler
+
+	return handler
+}
+
+type interactionResult struct {
+	response *discord.InteractionResponse
+	err      error
+
+	// duplicate is true if the response is from an earlier delivery of the interaction.
+	duplicate bool
+}
+
 func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
@@ -21,6 +80,8 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	sub.Start()
+
 	start := time.Now()
 
 	defer r.Body.Close()
@@ -34,9 +95,9 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	verified := sub.verifySignature(r, body)
-	if !verified {
-		sub.Logger.Warn().Msg("Sender passed invalid signature")
+	err = sub.verifySignature(r, body)
+	if err != nil {
+		sub.Logger.Warn().Err(err).Msg("Sender passed invalid signature")
 
 		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 
@@ -45,64 +106,194 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 
 	var interaction discord.Interaction
 
-	defer func() {
-		elapsed := float64(time.Since(start)) / float64(time.Second)
+	err = json.Unmarshal(body, &interaction)
+	if err != nil {
+		sub.Logger.Warn().Err(err).Msg("Failed to parse interaction")
+
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
 
-		var commandName string
+	if interaction.Type == discord.InteractionTypePing {
+		w.Header().Add("Content-Type", "application/json")
+		_, _ = w.Write(InteractionPongResponse)
 
-		var guildID string
+		return
+	}
 
-		var userID string
+	if !sub.beginInteraction() {
+		http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
 
-		if interaction.Data != nil {
-			commandName = interaction.Data.Name
-		}
+		return
+	}
 
-		if interaction.GuildID != nil {
-			guildID = strconv.FormatInt(int64(*interaction.GuildID), 10)
+	ctx := sub.newInteractionContext(interaction, body)
+	ctx = AddURLToContext(ctx, *r.URL)
+
+	sub.dispatchInteraction(ctx, interaction, start, func(response *discord.InteractionResponse, err error) {
+		if response == nil && IsInteractionAcknowledged(ctx) {
+			// The handler has already responded, such as by opening a modal.
+			w.WriteHeader(http.StatusNoContent)
+
+			return
 		}
 
-		if interaction.User != nil {
-			userID = strconv.FormatInt(int64(interaction.User.ID), 10)
+		sub.writeInteractionResponse(sub.getLogger(ctx), w, response, err)
+	})
+}
+
+// newInteractionContext creates the base context for processing an interaction.
+func (sub *Subway) newInteractionContext(interaction discord.Interaction, rawInteraction []byte) context.Context {
+	// Interactions are not cancelled with the subway context, so they are able
+	// to finish whilst shutting down.
+	ctx := context.WithoutCancel(sub.Context)
+	ctx = AddLoggerToContext(ctx, sub.newInteractionLogger(interaction))
+	ctx = sub.addApplicationToContext(ctx, interaction)
+	ctx = AddRawInteractionToContext(ctx, rawInteraction)
+	ctx = AddInteractionAcknowledgementToContext(ctx, &InteractionAcknowledgement{})
+
+	return ctx
+}
+
+// newInteractionLogger creates a logger with the metadata of an interaction.
+func (sub *Subway) newInteractionLogger(interaction discord.Interaction) *zerolog.Logger {
+	logContext := sub.Logger.With().
+		Int64("interaction_id", int64(interaction.ID)).
+		Int("interaction_type", int(interaction.Type)).
+		Int64("application_id", int64(interaction.ApplicationID))
+
+	if interaction.Data != nil {
+		switch interaction.Type {
+		case discord.InteractionTypeApplicationCommand, discord.InteractionTypeApplicationCommandAutocomplete:
+			commandTree := constructCommandTree(interaction.Data.Options, []string{interaction.Data.Name})
+			logContext = logContext.Str("command", strings.Join(commandTree, " "))
+		default:
+			logContext = logContext.Str("custom_id", interaction.Data.CustomID)
 		}
+	}
+
+	if interaction.GuildID != nil {
+		logContext = logContext.Int64("guild_id", int64(*interaction.GuildID))
+	}
+
+	if userID := getInteractionUserID(interaction); !userID.IsNil() {
+		logContext = logContext.Int64("user_id", int64(userID))
+	}
+
+	logger := logContext.Logger()
+
+	return &logger
+}
+
+// getLogger returns the logger for the interaction in the context,
+// falling back to the subway logger.
+func (sub *Subway) getLogger(ctx context.Context) *zerolog.Logger {
+	if logger, ok := ctx.Value(LoggerKey).(*zerolog.Logger); ok {
+		return logger
+	}
+
+	return &sub.Logger
+}
+
+// getInteractionUserID returns the ID of the user who created the interaction.
+// Interactions in guilds only provide the member.
+func getInteractionUserID(interaction discord.Interaction) discord.Snowflake {
+	switch {
+	case interaction.User != nil:
+		return interaction.User.ID
+	case interaction.Member != nil && interaction.Member.User != nil:
+		return interaction.Member.User.ID
+	default:
+		return 0
+	}
+}
 
-		subwayInteractionProcessingTimeName.WithLabelValues(commandName, guildID, userID).Observe(elapsed)
+// dispatchInteraction processes an interaction and passes the response to respond. If the handler
+// is taking too long, a deferred response is passed instead and the handler response is delivered
+// later through the interaction webhook. This blocks until respond has been called.
+// beginInteraction must have been called beforehand, as this will end the in-flight interaction.
+func (sub *Subway) dispatchInteraction(ctx context.Context, interaction discord.Interaction, start time.Time, respond func(response *discord.InteractionResponse, err error)) {
+	// The interaction is processed separately, so we are able to defer the response
+	// if the handler is taking too long.
+	results := make(chan interactionResult, 1)
+
+	go func() {
+		results <- sub.processInteractionOnce(ctx, interaction, start)
 	}()
 
-	err = json.Unmarshal(body, &interaction)
-	if err != nil {
-		sub.Logger.Warn().Err(err).Msg("Failed to parse interaction")
+	var deferTimeout <-chan time.Time
 
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	deferredResponse := sub.getDeferredResponse(ctx, interaction)
+	if deferredResponse != nil {
+		timer := time.NewTimer(sub.deferResponseAfter)
+		defer timer.Stop()
 
-		return
+		deferTimeout = timer.C
 	}
 
-	var guildID string
+	select {
+	case result := <-results:
+		respond(result.response, result.err)
+		sub.endInteraction()
+	case <-deferTimeout:
+		if IsInteractionAcknowledged(ctx) {
+			respond(nil, nil)
 
-	var userID string
+			go func() {
+				<-results
+				sub.endInteraction()
+			}()
 
-	if interaction.Type == discord.InteractionTypePing {
-		w.Header().Add("Content-Type", "application/json")
-		_, _ = w.Write(InteractionPongResponse)
+			return
+		}
 
-		return
+		sub.getLogger(ctx).Debug().Msg("Deferring interaction response")
+
+		respond(deferredResponse, nil)
+
+		go func() {
+			defer sub.endInteraction()
+
+			result := <-results
+			if result.duplicate {
+				// The first delivery is responsible for delivering the response.
+				return
+			}
+
+			sub.deliverDeferredResponse(ctx, interaction, deferredResponse.Type, result.response, result.err)
+		}()
 	}
+}
 
-	var response *discord.InteractionResponse
+// processInteraction dispatches the interaction to its handler and records metrics for it.
+func (sub *Subway) processInteraction(ctx context.Context, interaction discord.Interaction, start time.Time) (response *discord.InteractionResponse, err error) {
+	release, ok := sub.acquireInteractionSlot(ctx, interaction)
+	if !ok {
+		sub.getLogger(ctx).Warn().Msg("Interaction exceeded queue timeout")
 
-	ctx := sub.Context
-	ctx = AddURLToContext(ctx, *r.URL)
+		subwayInteractionRejectedTotal.Add(1)
 
-	switch interaction.Type {
-	case discord.InteractionTypeApplicationCommand, discord.InteractionTypeApplicationCommandAutocomplete:
-		response, err = sub.ProcessApplicationCommandInteraction(ctx, interaction)
-	case discord.InteractionTypeMessageComponent:
-		response, err = sub.ProcessMessageComponentInteraction(ctx, interaction)
-	// case discord.InteractionTypeModalSubmit:
-	// 	// not implemented
-	default:
-		sub.Logger.Warn().Int("interaction_type", int(interaction.Type)).Msg("Missing interaction handler")
+		// Autocomplete interactions are not able to receive a message.
+		if interaction.Type == discord.InteractionTypeApplicationCommandAutocomplete {
+			return nil, ErrInteractionQueueTimeout
+		}
+
+		return sub.BusyResponse, nil
+	}
+
+	defer release()
+
+	response, err = sub.handleInteraction(ctx, interaction)
+
+	var commandName string
+
+	var guildID string
+
+	var userID string
+
+	if interaction.Data != nil {
+		commandName = interaction.Data.Name
 	}
 
 	if interaction.GuildID != nil {
@@ -113,28 +304,80 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 		userID = strconv.FormatInt(int64(interaction.User.ID), 10)
 	}
 
+	elapsed := float64(time.Since(start)) / float64(time.Second)
+
+	subwayInteractionProcessingTimeName.WithLabelValues(commandName, guildID, userID).Observe(elapsed)
+	subwayInteractionTotal.WithLabelValues(commandName, guildID, userID).Add(1)
+
+	if err != nil {
+		sub.getLogger(ctx).Error().Err(err).Msg("Failed to process interaction")
+
+		subwayFailedInteractionTotal.Add(1)
+	} else {
+		subwaySuccessfulInteractionTotal.Add(1)
+	}
+
+	return response, err
+}
+
+// handleInteraction passes the interaction to its handler. Panics that are not recovered by the
+// command, such as in component listeners or interaction hooks, are passed to the root error handler.
+func (sub *Subway) handleInteraction(ctx context.Context, interaction discord.Interaction) (response *discord.InteractionResponse, err error) {
 	defer func() {
-		if err != nil {
-			subwayFailedInteractionTotal.Add(1)
-		} else {
-			subwaySuccessfulInteractionTotal.Add(1)
+		errorValue := recover()
+		if errorValue != nil {
+			response, err = sub.handlePanic(ctx, sub.getCommands(ctx), interaction, errorValue)
 		}
 	}()
 
-	subwayInteractionTotal.WithLabelValues(interaction.Data.Name, guildID, userID).Add(1)
-
-	if err != nil {
-		sub.Logger.Error().Err(err).Msg("Failed to process interaction")
+	switch interaction.Type {
+	case discord.InteractionTypeApplicationCommand, discord.InteractionTypeApplicationCommandAutocomplete:
+		return sub.ProcessApplicationCommandInteraction(ctx, interaction)
+	case discord.InteractionTypeMessageComponent:
+		return sub.ProcessMessageComponentInteraction(ctx, interaction)
+	case discord.InteractionTypeModalSubmit:
+		return sub.ProcessModalSubmitInteraction(ctx, interaction)
+	default:
+		sub.getLogger(ctx).Warn().Msg("Missing interaction handler")
+	}
 
-		w.WriteHeader(http.StatusNoContent)
+	return nil, nil
+}
 
-		return
+// handlePanic logs a recovered panic and propagates it as a PanicError, returning the response
+// of the error handlers. A panic within the error handlers is logged and no response is returned,
+// but the PanicError is still returned.
+func (sub *Subway) handlePanic(ctx context.Context, ic *InteractionCommandable, interaction discord.Interaction, errorValue interface{}) (response *discord.InteractionResponse, err error) {
+	panicError := PanicError{
+		Recover: errorValue,
+		Stack:   debug.Stack(),
 	}
 
+	sub.getLogger(ctx).Error().
+		Interface("errorValue", errorValue).
+		Str("stack", string(panicError.Stack)).
+		Msg("Recovered panic on event dispatch")
+
+	defer func() {
+		errorValue := recover()
+		if errorValue != nil {
+			sub.getLogger(ctx).Error().Interface("errorValue", errorValue).Msg("Recovered panic in error handler")
+
+			response = nil
+			err = panicError
+		}
+	}()
+
+	return ic.propagateError(ctx, sub, interaction, panicError), panicError
+}
+
+// writeInteractionResponse writes the response of an interaction to the HTTP request.
+func (sub *Subway) writeInteractionResponse(logger *zerolog.Logger, w http.ResponseWriter, response *discord.InteractionResponse, err error) {
 	if response != nil {
+		// The response may be from an error handler, so it is sent even if an error occurred.
 		resp, err := json.Marshal(response)
 		if err != nil {
-			sub.Logger.Warn().Err(err).Msg("Failed to marshal response")
+			logger.Warn().Err(err).Msg("Failed to marshal response")
 
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 
@@ -145,10 +388,12 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 
 		_, err = w.Write(resp)
 		if err != nil {
-			sub.Logger.Warn().Err(err).Msg("Failed to write response")
+			logger.Warn().Err(err).Msg("Failed to write response")
 		}
 	} else {
-		sub.Logger.Warn().Msg("No response to send")
+		if err == nil {
+			logger.Warn().Msg("No response to send")
+		}
 
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -157,7 +402,7 @@ func (sub *Subway) HandleSubwayRequest(w http.ResponseWriter, r *http.Request) {
 func (sub *Subway) NewGRPCContext(ctx context.Context) *sandwich.GRPCContext {
 	return &sandwich.GRPCContext{
 		Context:        ctx,
-		Logger:         sub.Logger,
+		Logger:         *sub.getLogger(ctx),
 		SandwichClient: sub.SandwichClient,
 		GRPCInterface:  sub.GRPCInterface,
 	}
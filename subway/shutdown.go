@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Shutdown gracefully stops subway. This will stop accepting new interactions, wait for
+// in-flight interactions to finish, unload all cogs and then close the admin and prometheus servers.
+// Calling Shutdown multiple times will wait for the first call to complete.
+func (sub *Subway) Shutdown(ctx context.Context) error {
+	sub.shutdownOnce.Do(func() {
+		sub.shutdownErr = sub.shutdown(ctx)
+
+		close(sub.shutdownComplete)
+	})
+
+	<-sub.shutdownComplete
+
+	return sub.shutdownErr
+}
+
+func (sub *Subway) shutdown(ctx context.Context) error {
+	sub.Logger.Info().Msg("Shutting down subway")
+
+	var errs []error
+
+	// Stop accepting new interactions.
+	sub.inFlightMu.Lock()
+	sub.shuttingDown = true
+	sub.inFlightMu.Unlock()
+
+	sub.serversMu.Lock()
+	server := sub.server
+	prometheusServer := sub.prometheusServer
+	adminServer := sub.adminServer
+	sub.serversMu.Unlock()
+
+	if server != nil {
+		err := server.Shutdown(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown subway server: %w", err))
+		}
+	}
+
+	// Wait for in-flight interactions, including deferred responses.
+	err := waitWithContext(ctx, &sub.inFlight)
+	if err != nil {
+		sub.Logger.Warn().Err(err).Msg("Timed out waiting for in-flight interactions")
+
+		errs = append(errs, fmt.Errorf("failed to wait for in-flight interactions: %w", err))
+	}
+
+	// Unload cogs.
+	wg := &sync.WaitGroup{}
+
+	sub.unloadCogs(sub.Cogs, wg)
+
+	for _, application := range sub.GetApplications() {
+		sub.unloadCogs(application.Cogs, wg)
+	}
+
+	err = waitWithContext(ctx, wg)
+	if err != nil {
+		sub.Logger.Warn().Err(err).Msg("Timed out waiting for cogs to unload")
+
+		errs = append(errs, fmt.Errorf("failed to wait for cogs to unload: %w", err))
+	}
+
+	if adminServer != nil {
+		err = adminServer.Shutdown(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown admin server: %w", err))
+		}
+	}
+
+	if prometheusServer != nil {
+		err = prometheusServer.Shutdown(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown prometheus server: %w", err))
+		}
+	}
+
+	sub.Logger.Info().Msg("Subway has shutdown")
+
+	return errors.Join(errs...)
+}
+
+// unloadCogs calls BotUnload on all cogs that implement it.
+func (sub *Subway) unloadCogs(cogs map[string]Cog, wg *sync.WaitGroup) {
+	for _, cog := range cogs {
+		if cast, ok := cog.(CogWithBotUnload); ok {
+			sub.Logger.Info().Str("cog", cog.CogInfo().Name).Msg("Unloading Cog")
+
+			cast.BotUnload(sub, wg)
+		}
+	}
+}
+
+// shutdownOnContextDone shuts down subway once the subway context has been cancelled.
+func (sub *Subway) shutdownOnContextDone() {
+	select {
+	case <-sub.Context.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), sub.shutdownTimeout)
+		defer cancel()
+
+		err := sub.Shutdown(ctx)
+		if err != nil {
+			sub.Logger.Error().Err(err).Msg("Failed to gracefully shutdown subway")
+		}
+	case <-sub.shutdownComplete:
+	}
+}
+
+// beginInteraction marks an interaction as in-flight. Returns false if subway is shutting down,
+// in which case the interaction should not be processed.
+func (sub *Subway) beginInteraction() bool {
+	sub.inFlightMu.RLock()
+	defer sub.inFlightMu.RUnlock()
+
+	if sub.shuttingDown {
+		return false
+	}
+
+	sub.inFlight.Add(1)
+
+	return true
+}
+
+// endInteraction marks an in-flight interaction as finished.
+func (sub *Subway) endInteraction() {
+	sub.inFlight.Done()
+}
+
+// waitWithContext waits for a WaitGroup, returning early if the context is done.
+func waitWithContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
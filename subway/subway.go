@@ -2,11 +2,9 @@ package internal
 
 import (
 	"context"
-	"crypto/ed25519"
-	"encoding/hex"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
@@ -14,6 +12,7 @@ import (
 	protobuf "github.com/WelcomerTeam/Sandwich-Daemon/protobuf"
 	sandwich "github.com/WelcomerTeam/Sandwich/sandwich"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
 )
 
 // VERSION follows semantic versioning.
@@ -24,6 +23,10 @@ const (
 	PermissionWrite    = 0o600
 
 	defaultMaximumInteractionAge = 15 * time.Minute
+	defaultDeferResponseAfter    = 2 * time.Second
+	defaultShutdownTimeout       = 30 * time.Second
+	defaultMaximumTimestampSkew  = 5 * time.Minute
+	defaultQueueTimeout          = 1500 * time.Millisecond
 )
 
 type Subway struct {
@@ -37,20 +40,86 @@ type Subway struct {
 
 	Cogs map[string]Cog `json:"-"`
 
+	// Applications are additional bot applications served by subway. Interactions
+	// for applications that are not registered use Commands.
+	ApplicationsMu sync.RWMutex                       `json:"-"`
+	Applications   map[discord.Snowflake]*Application `json:"-"`
+
 	SandwichClient protobuf.SandwichClient `json:"-"`
 	GRPCInterface  sandwich.GRPC           `json:"-"`
-	RESTInterface  discord.RESTInterface   `json:"-"`
-	EmptySession   *discord.Session        `json:"-"`
+
+	// SandwichConnection is the connection used by SandwichClient. If provided,
+	// its state is reported by the readiness endpoint.
+	SandwichConnection *grpc.ClientConn `json:"-"`
+
+	RESTInterface discord.RESTInterface `json:"-"`
+	EmptySession  *discord.Session      `json:"-"`
 
 	ComponentListenersMu sync.RWMutex
 	ComponentListeners   map[string]*ComponentListener
 
+	ModalListenersMu sync.RWMutex
+	ModalListeners   map[string]*ModalListener
+
 	OnBeforeInteraction InteractionRequestHandler
 	OnAfterInteraction  InteractionResponseHandler
 
+	// Middlewares wrap HandleSubwayRequest when subway is served as a http.Handler.
+	// The first middleware is the outermost. Use should be used to add middlewares
+	// once subway has been created, as the middleware chain is only built once.
+	Middlewares []Middleware
+
+	handlerMu sync.RWMutex
+	handler   http.Handler
+
+	// Server is used as the base server for ListenAndServe.
+	Server *http.Server
+
+	// DedupeStore is used to ignore duplicate deliveries of an interaction.
+	DedupeStore DedupeStore
+
+	// BusyResponse is sent when an interaction has waited in the queue for too long.
+	BusyResponse *discord.InteractionResponse
+
+	// DevelopmentGuildID is the guild development commands are registered in.
+	DevelopmentGuildID discord.Snowflake
+
+	// Guilds commands have been synced to, by application ID, so guilds that no
+	// longer have any commands are cleared.
+	syncedCommandGuildsMu sync.Mutex
+	syncedCommandGuilds   map[discord.Snowflake]map[discord.Snowflake]bool
+	commandGuildIDs       []discord.Snowflake
+
 	// Environment Variables.
-	publicKeys        []ed25519.PublicKey
-	prometheusAddress string
+	publicKeys           publicKeySet
+	maximumTimestampSkew time.Duration
+
+	prometheusAddress  string
+	adminToken         string
+	adminAddress       string
+	token              string
+	commandDriftCheck  *CommandDriftCheckOptions
+	deferResponseAfter time.Duration
+	shutdownTimeout    time.Duration
+
+	limiter      *concurrencyLimiter
+	queueTimeout time.Duration
+
+	serversMu        sync.Mutex
+	server           *http.Server
+	prometheusServer *http.Server
+	adminServer      *http.Server
+
+	// Tracks interactions that are still being processed, so they can be drained on shutdown.
+	inFlightMu   sync.RWMutex
+	inFlight     sync.WaitGroup
+	shuttingDown bool
+
+	startOnce sync.Once
+
+	shutdownOnce     sync.Once
+	shutdownComplete chan struct{}
+	shutdownErr      error
 }
 
 // SubwayOptions represents the options to create a new subway service.
@@ -59,16 +128,82 @@ type SubwayOptions struct {
 	RESTInterface  discord.RESTInterface
 	Logger         zerolog.Logger
 
+	// Connection used by SandwichClient, used to report readiness.
+	SandwichConnection *grpc.ClientConn
+
 	OnBeforeInteraction InteractionRequestHandler
 	OnAfterInteraction  InteractionResponseHandler
 
+	// Middlewares to wrap each request with. The first middleware is the outermost.
+	Middlewares []Middleware
+
+	// Server to use for ListenAndServe, allowing for timeouts, header limits and TLS
+	// to be configured. The Addr and Handler will be overridden.
+	// If TLSConfig is set, the server will serve TLS using its certificates.
+	Server *http.Server
+
+	// Store used to dedupe interactions that are delivered more than once.
+	// Defaults to an in-memory store.
+	DedupeStore DedupeStore
+
+	// Maximum number of interactions processed at once. Interactions over this limit are
+	// queued by their command priority. Defaults to 0, which does not limit interactions.
+	MaxConcurrentInteractions int
+
+	// Maximum time an interaction can wait in the queue before BusyResponse is sent.
+	// Defaults to 1.5 seconds, this should be lower than DeferResponseAfter.
+	QueueTimeout time.Duration
+
+	// Response sent when an interaction has waited in the queue for too long.
+	// Defaults to DefaultBusyResponse.
+	BusyResponse *discord.InteractionResponse
+
 	PublicKeys        string
 	PrometheusAddress string
 
+	// Guild that commands with a development scope are registered in when syncing.
+	// Development commands are not registered if this is not set.
+	DevelopmentGuildID discord.Snowflake
+
+	// Guilds that may have commands from a previous sync, such as a previous development guild.
+	// When syncing, these are cleared if they no longer have any commands. Guilds synced since
+	// subway started are always cleared.
+	CommandGuildIDs []discord.Snowflake
+
+	// Compares the commands registered with discord to the command tree when subway is started.
+	// Drift is logged and recorded in the subway_command_drift metric. Applications are checked
+	// using their token. Disabled if nil.
+	CommandDriftCheck *CommandDriftCheckOptions
+
+	// Bearer token for the admin API, served at /admin/ on AdminAddress. The admin API is disabled if empty.
+	AdminToken string
+
+	// Address the admin API is served at by ListenAndServe. This should not be reachable by
+	// discord or the public. If empty, the admin API is only served by mounting AdminHandler.
+	AdminAddress string
+
+	// Token used for requests made when converting arguments, such as fetching messages.
+	// Applications use their own token. Token must have "Bot " added.
+	Token string
+
+	// Maximum difference between the signature timestamp of a request and the current time.
+	// Requests outside of this window are rejected to prevent replays.
+	// Defaults to 5 minutes. Set to a negative duration to disable the check.
+	MaximumTimestampSkew time.Duration
+
 	// Maximum age for component listeners. Defaults to 15 minutes.
 	// This is the absolute maximum age of a component listener,
 	// ignoring a listener with a longer age.
 	MaximumInteractionAge time.Duration
+
+	// Duration to wait for a handler before a deferred response is sent to discord.
+	// The handler response is then delivered by editing the original response.
+	// Defaults to 2 seconds. Set to a negative duration to disable deferring.
+	DeferResponseAfter time.Duration
+
+	// Maximum time to wait for in-flight interactions and cogs to unload when
+	// shutting down after the context has been cancelled. Defaults to 30 seconds.
+	ShutdownTimeout time.Duration
 }
 
 func NewSubway(ctx context.Context, options SubwayOptions) (*Subway, error) {
@@ -81,41 +216,98 @@ func NewSubway(ctx context.Context, options SubwayOptions) (*Subway, error) {
 		SandwichClient: options.SandwichClient,
 		GRPCInterface:  sandwich.NewDefaultGRPCClient(),
 
+		SandwichConnection: options.SandwichConnection,
+
 		ComponentListenersMu: sync.RWMutex{},
 		ComponentListeners:   make(map[string]*ComponentListener),
 
+		ModalListenersMu: sync.RWMutex{},
+		ModalListeners:   make(map[string]*ModalListener),
+
 		OnBeforeInteraction: options.OnBeforeInteraction,
 		OnAfterInteraction:  options.OnAfterInteraction,
 
+		Middlewares: options.Middlewares,
+		Server:      options.Server,
+		DedupeStore: options.DedupeStore,
+
+		BusyResponse: options.BusyResponse,
+
+		DevelopmentGuildID: options.DevelopmentGuildID,
+		queueTimeout:       options.QueueTimeout,
+
+		syncedCommandGuilds: make(map[discord.Snowflake]map[discord.Snowflake]bool),
+		commandGuildIDs:     options.CommandGuildIDs,
+
 		prometheusAddress: options.PrometheusAddress,
+		adminToken:        options.AdminToken,
+		adminAddress:      options.AdminAddress,
+		token:             options.Token,
+		commandDriftCheck: options.CommandDriftCheck,
 
 		Commands:   SetupInteractionCommandable(nil),
 		Converters: NewInteractionConverters(),
 
 		Cogs: make(map[string]Cog),
+
+		ApplicationsMu: sync.RWMutex{},
+		Applications:   make(map[discord.Snowflake]*Application),
+
+		shutdownTimeout:  options.ShutdownTimeout,
+		shutdownComplete: make(chan struct{}),
 	}
 
-	// Setup public keys
-	publicKeys := strings.Split(options.PublicKeys, ",")
-	sub.publicKeys = make([]ed25519.PublicKey, 0, len(publicKeys))
+	sub.Commands.converters = sub.Converters
 
-	for _, publicKey := range publicKeys {
-		hex, err := hex.DecodeString(publicKey)
-		if err != nil {
-			return nil, ErrInvalidPublicKey
-		}
+	// Setup public keys
+	err := sub.SetPublicKeys(options.PublicKeys)
+	if err != nil {
+		return nil, err
+	}
 
-		sub.publicKeys = append(sub.publicKeys, ed25519.PublicKey(hex))
+	switch {
+	case options.MaximumTimestampSkew == 0:
+		sub.maximumTimestampSkew = defaultMaximumTimestampSkew
+	case options.MaximumTimestampSkew > 0:
+		sub.maximumTimestampSkew = options.MaximumTimestampSkew
 	}
 
 	// Setup sessions
 	sub.EmptySession = discord.NewSession("", sub.RESTInterface)
 
+	switch {
+	case options.DeferResponseAfter == 0:
+		sub.deferResponseAfter = defaultDeferResponseAfter
+	case options.DeferResponseAfter > 0:
+		sub.deferResponseAfter = options.DeferResponseAfter
+	}
+
 	if options.MaximumInteractionAge <= 0 {
 		options.MaximumInteractionAge = defaultMaximumInteractionAge
 	}
 
+	if options.MaxConcurrentInteractions > 0 {
+		sub.limiter = newConcurrencyLimiter(options.MaxConcurrentInteractions)
+	}
+
+	if sub.queueTimeout <= 0 {
+		sub.queueTimeout = defaultQueueTimeout
+	}
+
+	if sub.BusyResponse == nil {
+		sub.BusyResponse = DefaultBusyResponse
+	}
+
+	if sub.DedupeStore == nil {
+		sub.DedupeStore = NewMemoryDedupeStore()
+	}
+
+	if sub.shutdownTimeout <= 0 {
+		sub.shutdownTimeout = defaultShutdownTimeout
+	}
+
 	go sub.InteractionCleanupJob(ctx, options.MaximumInteractionAge)
+	go sub.shutdownOnContextDone()
 
 	return sub, nil
 }
@@ -141,7 +333,7 @@ func (sub *Subway) cleanupInteractions(maximumAge time.Duration) {
 	deletedKeys := []string{}
 
 	for i, k := range sub.ComponentListeners {
-		if k.expiresAt.After(now) || k.createdAt.Add(maximumAge).After(now) {
+		if k.expiresAt.Before(now) || k.createdAt.Add(maximumAge).Before(now) {
 			deletedKeys = append(deletedKeys, i)
 		}
 	}
@@ -155,47 +347,112 @@ func (sub *Subway) cleanupInteractions(maximumAge time.Duration) {
 		}
 		sub.ComponentListenersMu.Unlock()
 	}
+
+	sub.ModalListenersMu.RLock()
+
+	expiredModals := []*ModalListener{}
+
+	for _, k := range sub.ModalListeners {
+		if k.expiresAt.Before(now) || k.createdAt.Add(maximumAge).Before(now) {
+			expiredModals = append(expiredModals, k)
+		}
+	}
+
+	sub.ModalListenersMu.RUnlock()
+
+	for _, listener := range expiredModals {
+		listener.Cancel()
+	}
+}
+
+// Start starts the background services of subway, such as the command drift check. This is
+// called by ListenAndServe and when the first interaction is handled, so subway served as a
+// http.Handler or through an InteractionSource is also started. Commands should be registered
+// beforehand. Calling Start more than once has no effect.
+func (sub *Subway) Start() {
+	sub.startOnce.Do(func() {
+		sub.StartTime = time.Now().UTC()
+
+		go sub.checkCommandDriftOnStartup()
+	})
 }
 
 // Listen handles starting up the webserver and services for you.
+// This will block until the server has been shutdown, either by cancelling
+// the context or by calling Shutdown.
 func (sub *Subway) ListenAndServe(route, host string) error {
 	if route == "" {
 		route = "/"
 	}
 
-	sub.StartTime = time.Now().UTC()
 	sub.Logger.Info().Msgf("Starting subway Version %s", VERSION)
 
-	// Setup Prometheus
-	go sub.SetupPrometheus()
+	// Setup Prometheus and the admin API. Servers are created before serving,
+	// so Shutdown is always able to close them.
+	prometheusServer := sub.newPrometheusServer()
+	go sub.servePrometheus(prometheusServer)
+
+	if sub.adminToken != "" && sub.adminAddress != "" {
+		adminServer := sub.newAdminServer()
+		go sub.serveAdmin(adminServer)
+	}
+
+	sub.Start()
 
 	sub.Logger.Info().Msgf("Serving subway at %s", host)
 
 	subwayMux := http.NewServeMux()
-	subwayMux.HandleFunc(route, sub.HandleSubwayRequest)
+	subwayMux.Handle(route, sub)
+	subwayMux.HandleFunc("/healthz", sub.HandleHealthRequest)
+	subwayMux.HandleFunc("/readyz", sub.HandleReadyRequest)
 
-	err := http.ListenAndServe(host, subwayMux)
-	if err != nil {
-		sub.Logger.Error().Str("host", sub.prometheusAddress).Err(err).Msg("Failed to serve subway server")
+	server := sub.Server
+	if server == nil {
+		server = &http.Server{}
+	}
+
+	server.Addr = host
+	server.Handler = subwayMux
+
+	sub.serversMu.Lock()
+	sub.server = server
+	sub.serversMu.Unlock()
+
+	var err error
+
+	if server.TLSConfig != nil {
+		err = server.ListenAndServeTLS("", "")
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		sub.Logger.Error().Str("host", host).Err(err).Msg("Failed to serve subway server")
 
 		return fmt.Errorf("failed to serve sub: %w", err)
 	}
 
-	return nil
+	// The server has been closed by Shutdown, wait for it to finish draining.
+	<-sub.shutdownComplete
+
+	return sub.shutdownErr
 }
 
-// SyncCommands syncs all registered commands with the discord API.
+// SyncCommands syncs all registered commands with the discord API. Global commands are
+// registered globally and scoped commands are registered in their guilds.
 // Use sandwichClient.FetchIdentifier to get the token for an identifier.
 // Token must have "Bot " added.
 func (sub *Subway) SyncCommands(ctx context.Context, token string, applicationID discord.Snowflake) error {
 	session := discord.NewSession(token, sub.RESTInterface)
 
-	applicationCommands := sub.Commands.MapApplicationCommands()
+	scopedCommands := sub.addStaleCommandGuilds(applicationID, sub.Commands.MapScopedApplicationCommands(sub.DevelopmentGuildID))
 
-	_, err := discord.BulkOverwriteGlobalApplicationCommands(ctx, session, applicationID, applicationCommands)
+	err := syncScopedApplicationCommands(ctx, session, applicationID, scopedCommands)
 	if err != nil {
-		return fmt.Errorf("failed to bulk overwrite commands: %w", err)
+		return err
 	}
 
+	sub.recordSyncedCommandGuilds(applicationID, scopedCommands)
+
 	return nil
 }